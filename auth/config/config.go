@@ -1,16 +1,65 @@
 package config
 
 import (
+	"auth/mail"
+	"auth/oidc"
+	"auth/passwords"
+	"auth/throttle"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	ServerPort string
-	JWTSecret  string
 	UsersURL   string
+	// Общий секрет для подписи запросов между сервисами
+	ServiceTokenSecret string
+
+	JWTKeysDir      string
+	JWTActiveKID    string // необязательно: по умолчанию подписывает ключ с наибольшим kid
+	KeysReloadEvery time.Duration
+
+	DBHost     string
+	DBPort     string
+	DBUser     string
+	DBPassword string
+	DBName     string
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Адрес фронтенда для ссылок в письмах
+	AppURL                   string
+	RequireEmailVerification bool
+	VerifyTokenTTL           time.Duration
+	ResetTokenTTL            time.Duration
+	Mail                     mail.Config
+
+	// Защита от подбора пароля: счётчики по аккаунту и по IP
+	ThrottleStore   string // postgres или memory (один экземпляр, тесты)
+	AccountThrottle throttle.Policy
+	IPThrottle      throttle.Policy
+	// Прокси, которым доверяем X-Forwarded-For при определении IP клиента
+	TrustedProxies []string
+
+	// Пароли: политика и алгоритм хэширования. Хэши старого алгоритма
+	// или с меньшей стоимостью пересчитываются при входе.
+	PasswordPolicy passwords.Policy
+	PasswordHasher passwords.Hasher
+
+	// Двухфакторная аутентификация: название в приложении-аутентификаторе и
+	// требование второго фактора для модераторов и администраторов
+	MFAIssuer       string
+	RequireStaffMFA bool
+
+	// Внешний адрес сервиса (через шлюз) для redirect_uri провайдеров
+	PublicURL     string
+	OIDCProviders []oidc.Config
 }
 
 func LoadConfig() (*Config, error) {
@@ -18,14 +67,81 @@ func LoadConfig() (*Config, error) {
 
 	// return &Config{
 	// 	ServerPort: getEnv("AUTH_PORT", "8081"),
-	// 	JWTSecret:  getEnv("JWT_SECRET", "twitch-prime"),
 	// 	UsersURL:   getEnv("USERS_SERVICE_URL", "http://localhost:8082"),
 	// }, nil
 
 	cfg := &Config{
 		ServerPort: getEnv("SERVER_PORT"),
-		JWTSecret:  getEnv("JWT_SECRET"),
 		UsersURL:   getEnv("USERS_SERVICE_URL"),
+
+		ServiceTokenSecret: getEnv("SERVICE_TOKEN_SECRET"),
+
+		JWTKeysDir:      getEnvDefault("JWT_KEYS_DIR", "jwt-keys"),
+		JWTActiveKID:    os.Getenv("JWT_ACTIVE_KID"),
+		KeysReloadEvery: getEnvDuration("JWT_KEYS_RELOAD_INTERVAL", time.Minute),
+
+		DBHost:     getEnv("DB_HOST"),
+		DBPort:     getEnv("DB_PORT"),
+		DBUser:     getEnv("DB_USER"),
+		DBPassword: getEnv("DB_PASSWORD"),
+		DBName:     getEnv("DB_NAME"),
+
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		AppURL:                   getEnvDefault("APP_URL", "http://localhost:3000"),
+		RequireEmailVerification: os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true",
+		VerifyTokenTTL:           getEnvDuration("VERIFY_TOKEN_TTL", 48*time.Hour),
+		ResetTokenTTL:            getEnvDuration("RESET_TOKEN_TTL", time.Hour),
+		Mail: mail.Config{
+			Driver:       getEnvDefault("MAIL_DRIVER", "log"),
+			From:         getEnvDefault("MAIL_FROM", "no-reply@sudoku.local"),
+			SMTPHost:     os.Getenv("SMTP_HOST"),
+			SMTPPort:     getEnvDefault("SMTP_PORT", "587"),
+			SMTPUser:     os.Getenv("SMTP_USER"),
+			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+			Dir:          getEnvDefault("MAIL_DIR", "mail-out"),
+		},
+
+		ThrottleStore: getEnvDefault("LOGIN_THROTTLE_STORE", "postgres"),
+		// По умолчанию X-Forwarded-For не доверяем никому: иначе любой адрес
+		// из внутренней сети мог бы подменить IP и обойти счётчики по IP
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+	}
+
+	cfg.PasswordPolicy = passwords.Policy{MinLength: getEnvInt("PASSWORD_MIN_LENGTH", 8)}
+	cfg.PasswordHasher = passwords.Hasher{
+		Algorithm:  getEnvDefault("PASSWORD_HASH", passwords.Argon2id),
+		BcryptCost: getEnvInt("BCRYPT_COST", 12),
+		Argon2:     passwords.DefaultArgon2,
+	}
+	if a := cfg.PasswordHasher.Algorithm; a != passwords.Argon2id && a != passwords.Bcrypt {
+		return nil, fmt.Errorf("unknown PASSWORD_HASH %q", a)
+	}
+
+	cfg.MFAIssuer = getEnvDefault("MFA_ISSUER", "Sudoku")
+	cfg.RequireStaffMFA = os.Getenv("REQUIRE_STAFF_MFA") == "true"
+
+	cfg.PublicURL = strings.TrimSuffix(getEnvDefault("AUTH_PUBLIC_URL", "http://localhost:8080/auth"), "/")
+	cfg.OIDCProviders = loadOIDCProviders(cfg.PublicURL)
+
+	// С одного IP могут входить многие пользователи (NAT), поэтому пороги для IP выше
+	lockoutFor := getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	cfg.AccountThrottle = throttle.Policy{
+		FreeAttempts: getEnvInt("LOGIN_FREE_ATTEMPTS", 3),
+		BaseDelay:    time.Second,
+		MaxDelay:     getEnvDuration("LOGIN_MAX_DELAY", 5*time.Minute),
+		LockoutAfter: getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LockoutFor:   lockoutFor,
+		Window:       time.Hour,
+	}
+	cfg.IPThrottle = throttle.Policy{
+		FreeAttempts: getEnvInt("LOGIN_IP_FREE_ATTEMPTS", 20),
+		BaseDelay:    time.Second,
+		MaxDelay:     cfg.AccountThrottle.MaxDelay,
+		LockoutAfter: getEnvInt("LOGIN_IP_LOCKOUT_THRESHOLD", 100),
+		LockoutFor:   lockoutFor,
+		Window:       time.Hour,
 	}
 
 	return cfg, nil
@@ -38,3 +154,67 @@ func getEnv(key string) string {
 	}
 	return val
 }
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		panic(fmt.Sprintf("invalid duration in %s: %v", key, err))
+	}
+	return d
+}
+
+func getEnvDefault(key, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// getEnvList разбирает список через запятую; пустые элементы пропускаются
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func getEnvInt(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		panic(fmt.Sprintf("invalid integer in %s: %v", key, err))
+	}
+	return n
+}
+
+// loadOIDCProviders читает провайдеров из OIDC_PROVIDERS=google,mock и переменных
+// OIDC_<ИМЯ>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _SCOPES
+func loadOIDCProviders(publicURL string) []oidc.Config {
+	var providers []oidc.Config
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, oidc.Config{
+			Name:         name,
+			Issuer:       getEnv(prefix + "ISSUER"),
+			ClientID:     getEnv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  publicURL + "/oidc/" + name + "/callback",
+			Scopes:       strings.Fields(getEnvDefault(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+	return providers
+}
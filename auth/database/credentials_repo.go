@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetPasswordHash возвращает хэш пароля пользователя; пустая строка — пароля нет
+func (d *Database) GetPasswordHash(ctx context.Context, userID string) (string, error) {
+	var hash string
+	err := d.DB.GetContext(ctx, &hash, `SELECT password_hash FROM credentials WHERE user_id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get password hash: %w", err)
+	}
+	return hash, nil
+}
+
+func (d *Database) SetPasswordHash(ctx context.Context, userID, hash string) error {
+	_, err := d.DB.ExecContext(ctx, `
+		INSERT INTO credentials (user_id, password_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET password_hash = EXCLUDED.password_hash, updated_at = NOW()
+	`, userID, hash)
+	if err != nil {
+		return fmt.Errorf("set password hash: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrEmailTokenInvalid = errors.New("email token is invalid, expired or already used")
+
+// CreateEmailToken сохраняет токен; прежние неиспользованные токены того же назначения гасятся,
+// чтобы действовала только последняя ссылка
+func (d *Database) CreateEmailToken(ctx context.Context, t *models.EmailToken) error {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE email_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, t.UserID, t.Purpose); err != nil {
+		return fmt.Errorf("expire email tokens: %w", err)
+	}
+
+	if _, err := tx.NamedExecContext(ctx, `
+		INSERT INTO email_tokens (id, user_id, email, purpose, token_hash, expires_at)
+		VALUES (:id, :user_id, :email, :purpose, :token_hash, :expires_at)
+	`, t); err != nil {
+		return fmt.Errorf("create email token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// EmailTokenSentWithin сообщает, отправлялся ли пользователю токен этого назначения за последние within
+func (d *Database) EmailTokenSentWithin(ctx context.Context, userID, purpose string, within time.Duration) (bool, error) {
+	var exists bool
+	err := d.DB.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM email_tokens
+			WHERE user_id = $1 AND purpose = $2
+			  AND created_at > NOW() - make_interval(secs => $3)
+		)
+	`, userID, purpose, within.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("check recent email token: %w", err)
+	}
+	return exists, nil
+}
+
+// ConsumeEmailToken гасит действующий токен и выполняет fn; если fn вернула ошибку,
+// токен остаётся действительным
+func (d *Database) ConsumeEmailToken(ctx context.Context, hash, purpose string, fn func(t *models.EmailToken) error) error {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var t models.EmailToken
+	err = tx.GetContext(ctx, &t, `
+		SELECT id, user_id, email, purpose, token_hash, created_at, expires_at, used_at
+		FROM email_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL
+		FOR UPDATE
+	`, hash, purpose)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEmailTokenInvalid
+	}
+	if err != nil {
+		return fmt.Errorf("get email token: %w", err)
+	}
+	if t.ExpiresAt.Before(time.Now()) {
+		return ErrEmailTokenInvalid
+	}
+
+	if err := fn(&t); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE email_tokens SET used_at = NOW() WHERE id = $1`, t.ID); err != nil {
+		return fmt.Errorf("use email token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
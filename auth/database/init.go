@@ -0,0 +1,154 @@
+package database
+
+import (
+	"auth/config"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+type Database struct {
+	DB *sqlx.DB
+}
+
+func NewDatabase(cfg *config.Config) (*Database, error) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+
+	db, err := sqlx.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db: %w", err)
+	}
+
+	// ⏳ Ждём, пока БД станет доступна
+	const maxRetries = 10
+	for i := 0; i < maxRetries; i++ {
+		err := db.Ping()
+		if err == nil {
+			break
+		}
+		log.Printf("waiting for database... (%d/%d): %v", i+1, maxRetries, err)
+		time.Sleep(2 * time.Second)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping db after retries: %w", err)
+	}
+
+	database := &Database{DB: db}
+
+	if err := database.createTables(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	return database, nil
+}
+
+func (d *Database) createTables(ctx context.Context) error {
+	queries := []string{
+		// Храним только хэш токена; family_id объединяет цепочку ротаций одного входа
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			family_id VARCHAR(36) NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			token_version INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP,
+			replaced_by VARCHAR(36)
+		)`,
+		`CREATE INDEX IF NOT EXISTS refresh_tokens_user_idx ON refresh_tokens (user_id)`,
+		`CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens (family_id)`,
+		// Одноразовые токены из писем: подтверждение почты и сброс пароля
+		`CREATE TABLE IF NOT EXISTS email_tokens (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			email TEXT NOT NULL,
+			purpose TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS email_tokens_user_idx ON email_tokens (user_id, purpose)`,
+		// Счётчики неудачных входов; ключ — "account:<email>" или "ip:<адрес>"
+		`CREATE TABLE IF NOT EXISTS login_throttle (
+			key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure_at TIMESTAMP NOT NULL,
+			blocked_until TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS login_attempts (
+			id BIGSERIAL PRIMARY KEY,
+			email TEXT NOT NULL,
+			user_id VARCHAR(36),
+			ip TEXT NOT NULL,
+			user_agent TEXT NOT NULL DEFAULT '',
+			result TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS login_attempts_email_idx ON login_attempts (email, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS login_attempts_ip_idx ON login_attempts (ip, created_at DESC)`,
+		// Незавершённые входы через OIDC; state хранится в виде хэша
+		`CREATE TABLE IF NOT EXISTS oidc_states (
+			state_hash TEXT PRIMARY KEY,
+			provider TEXT NOT NULL,
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+		// Привязка аккаунта провайдера (имя провайдера + sub) к пользователю
+		`CREATE TABLE IF NOT EXISTS oidc_identities (
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			user_id VARCHAR(36) NOT NULL,
+			email TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (provider, subject)
+		)`,
+		`CREATE INDEX IF NOT EXISTS oidc_identities_user_idx ON oidc_identities (user_id)`,
+		// Хэши паролей; пользователи без строки здесь ещё не перенесены из сервиса users
+		// или входят только через OIDC
+		`CREATE TABLE IF NOT EXISTS credentials (
+			user_id VARCHAR(36) PRIMARY KEY,
+			password_hash TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT FALSE`,
+		// Двухфакторная аутентификация (TOTP); last_used_step защищает от повторного использования кода
+		`CREATE TABLE IF NOT EXISTS user_mfa (
+			user_id VARCHAR(36) PRIMARY KEY,
+			secret TEXT NOT NULL,
+			enabled_at TIMESTAMP,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+			id BIGSERIAL PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			code_hash TEXT NOT NULL,
+			used_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS mfa_recovery_codes_user_idx ON mfa_recovery_codes (user_id)`,
+		`CREATE TABLE IF NOT EXISTS mfa_challenges (
+			token_hash TEXT PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			email TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+	}
+
+	for _, q := range queries {
+		if _, err := d.DB.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("failed to exec query: %v\nquery: %s", err, q)
+		}
+	}
+
+	return nil
+}
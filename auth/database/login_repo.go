@@ -0,0 +1,82 @@
+package database
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Методы ниже реализуют throttle.Store поверх Postgres
+
+func (d *Database) GetThrottle(ctx context.Context, key string) (*models.ThrottleEntry, error) {
+	var e models.ThrottleEntry
+	err := d.DB.GetContext(ctx, &e, `
+		SELECT key, failures, last_failure_at, blocked_until FROM login_throttle WHERE key = $1
+	`, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get login throttle: %w", err)
+	}
+	return &e, nil
+}
+
+func (d *Database) AddThrottleFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+	var failures int
+	err := d.DB.GetContext(ctx, &failures, `
+		INSERT INTO login_throttle (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttle.last_failure_at < $3 THEN 1
+				ELSE login_throttle.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures
+	`, key, now, windowStart)
+	if err != nil {
+		return 0, fmt.Errorf("add login failure: %w", err)
+	}
+	return failures, nil
+}
+
+func (d *Database) BlockThrottle(ctx context.Context, key string, until time.Time) error {
+	if _, err := d.DB.ExecContext(ctx, `UPDATE login_throttle SET blocked_until = $2 WHERE key = $1`, key, until); err != nil {
+		return fmt.Errorf("block login: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) ResetThrottle(ctx context.Context, key string) error {
+	if _, err := d.DB.ExecContext(ctx, `DELETE FROM login_throttle WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("reset login throttle: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) PurgeThrottle(ctx context.Context, before time.Time) error {
+	_, err := d.DB.ExecContext(ctx, `
+		DELETE FROM login_throttle
+		WHERE last_failure_at < $1 AND (blocked_until IS NULL OR blocked_until < $1)
+	`, before)
+	if err != nil {
+		return fmt.Errorf("purge login throttle: %w", err)
+	}
+	return nil
+}
+
+// RecordLoginAttempt пишет попытку входа в журнал
+func (d *Database) RecordLoginAttempt(ctx context.Context, a *models.LoginAttempt) error {
+	_, err := d.DB.NamedExecContext(ctx, `
+		INSERT INTO login_attempts (email, user_id, ip, user_agent, result)
+		VALUES (:email, :user_id, :ip, :user_agent, :result)
+	`, a)
+	if err != nil {
+		return fmt.Errorf("record login attempt: %w", err)
+	}
+	return nil
+}
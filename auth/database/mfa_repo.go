@@ -0,0 +1,223 @@
+package database
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrMFAChallengeInvalid = errors.New("mfa challenge is invalid or expired")
+
+// GetMFA возвращает TOTP пользователя; nil — не подключалась
+func (d *Database) GetMFA(ctx context.Context, userID string) (*models.UserMFA, error) {
+	var m models.UserMFA
+	err := d.DB.GetContext(ctx, &m, `
+		SELECT user_id, secret, enabled_at, last_used_step FROM user_mfa WHERE user_id = $1
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get mfa: %w", err)
+	}
+	return &m, nil
+}
+
+// StartMFAEnrollment сохраняет новый секрет, ещё не подтверждённый кодом.
+// Включённую двухфакторную аутентификацию не трогает; false — она уже включена.
+func (d *Database) StartMFAEnrollment(ctx context.Context, userID, secret string) (bool, error) {
+	result, err := d.DB.ExecContext(ctx, `
+		INSERT INTO user_mfa (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return false, fmt.Errorf("start mfa enrollment: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// EnableMFA включает двухфакторную аутентификацию и заменяет коды восстановления
+func (d *Database) EnableMFA(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2 WHERE user_id = $1
+	`, userID, step); err != nil {
+		return fmt.Errorf("enable mfa: %w", err)
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep отмечает шаг кода использованным; false — код с этим или более поздним шагом уже принят
+func (d *Database) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result, err := d.DB.ExecContext(ctx, `
+		UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("use totp step: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// UseRecoveryCode гасит код восстановления; false — такого неиспользованного кода нет
+func (d *Database) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	result, err := d.DB.ExecContext(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (d *Database) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := d.DB.GetContext(ctx, &n, `
+		SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("count recovery codes: %w", err)
+	}
+	return n, nil
+}
+
+func (d *Database) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, h); err != nil {
+			return fmt.Errorf("insert recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// DisableMFA отключает двухфакторную аутентификацию и удаляет коды восстановления; false — она не была подключена
+func (d *Database) DisableMFA(ctx context.Context, userID string) (bool, error) {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("delete mfa: %w", err)
+	}
+	for _, q := range []string{
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM mfa_challenges WHERE user_id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+			return false, fmt.Errorf("disable mfa: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit tx: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (d *Database) CreateMFAChallenge(ctx context.Context, ch *models.MFAChallenge) error {
+	_, err := d.DB.NamedExecContext(ctx, `
+		INSERT INTO mfa_challenges (token_hash, user_id, email, expires_at)
+		VALUES (:token_hash, :user_id, :email, :expires_at)
+	`, ch)
+	if err != nil {
+		return fmt.Errorf("create mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// GetMFAChallenge возвращает действующий вызов и засчитывает попытку его пройти.
+// После maxAttempts попыток вызов удаляется и нужно заново ввести пароль.
+func (d *Database) GetMFAChallenge(ctx context.Context, hash string, maxAttempts int) (*models.MFAChallenge, error) {
+	var ch models.MFAChallenge
+	err := d.DB.GetContext(ctx, &ch, `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1
+		RETURNING token_hash, user_id, email, attempts, expires_at
+	`, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get mfa challenge: %w", err)
+	}
+
+	if ch.Attempts > maxAttempts || ch.ExpiresAt.Before(time.Now()) {
+		if err := d.DeleteMFAChallenge(ctx, hash); err != nil {
+			return nil, err
+		}
+		return nil, ErrMFAChallengeInvalid
+	}
+	return &ch, nil
+}
+
+func (d *Database) DeleteMFAChallenge(ctx context.Context, hash string) error {
+	if _, err := d.DB.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE token_hash = $1`, hash); err != nil {
+		return fmt.Errorf("delete mfa challenge: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) PurgeMFAChallenges(ctx context.Context, before time.Time) error {
+	if _, err := d.DB.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE expires_at < $1`, before); err != nil {
+		return fmt.Errorf("purge mfa challenges: %w", err)
+	}
+	return nil
+}
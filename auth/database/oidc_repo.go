@@ -0,0 +1,77 @@
+package database
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrOIDCStateInvalid = errors.New("oidc state is invalid or expired")
+
+func (d *Database) CreateOIDCState(ctx context.Context, s *models.OIDCState) error {
+	_, err := d.DB.NamedExecContext(ctx, `
+		INSERT INTO oidc_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES (:state_hash, :provider, :nonce, :code_verifier, :expires_at)
+	`, s)
+	if err != nil {
+		return fmt.Errorf("create oidc state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeOIDCState удаляет state и возвращает его: каждый state годится для одного входа
+func (d *Database) ConsumeOIDCState(ctx context.Context, hash, provider string) (*models.OIDCState, error) {
+	var s models.OIDCState
+	err := d.DB.GetContext(ctx, &s, `
+		DELETE FROM oidc_states WHERE state_hash = $1 AND provider = $2
+		RETURNING state_hash, provider, nonce, code_verifier, expires_at
+	`, hash, provider)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("consume oidc state: %w", err)
+	}
+	if s.ExpiresAt.Before(time.Now()) {
+		return nil, ErrOIDCStateInvalid
+	}
+	return &s, nil
+}
+
+// PurgeOIDCStates удаляет брошенные незавершённые входы
+func (d *Database) PurgeOIDCStates(ctx context.Context, before time.Time) error {
+	if _, err := d.DB.ExecContext(ctx, `DELETE FROM oidc_states WHERE expires_at < $1`, before); err != nil {
+		return fmt.Errorf("purge oidc states: %w", err)
+	}
+	return nil
+}
+
+// GetOIDCIdentity возвращает привязку аккаунта провайдера; nil — не привязан
+func (d *Database) GetOIDCIdentity(ctx context.Context, provider, subject string) (*models.OIDCIdentity, error) {
+	var i models.OIDCIdentity
+	err := d.DB.GetContext(ctx, &i, `
+		SELECT provider, subject, user_id, email FROM oidc_identities WHERE provider = $1 AND subject = $2
+	`, provider, subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get oidc identity: %w", err)
+	}
+	return &i, nil
+}
+
+func (d *Database) LinkOIDCIdentity(ctx context.Context, i *models.OIDCIdentity) error {
+	_, err := d.DB.NamedExecContext(ctx, `
+		INSERT INTO oidc_identities (provider, subject, user_id, email)
+		VALUES (:provider, :subject, :user_id, :email)
+		ON CONFLICT (provider, subject) DO UPDATE SET user_id = EXCLUDED.user_id, email = EXCLUDED.email
+	`, i)
+	if err != nil {
+		return fmt.Errorf("link oidc identity: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"auth/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	// ErrRefreshTokenReused — предъявлен уже использованный токен: вероятна кража,
+	// поэтому вся цепочка отзывается
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	// ErrRefreshTokenStale — после выдачи токена пользователь сменил пароль или вышел со всех устройств
+	ErrRefreshTokenStale = errors.New("refresh token revoked")
+)
+
+const refreshTokenColumns = `
+	id, user_id, family_id, token_hash, token_version, mfa,
+	created_at, expires_at, revoked_at, replaced_by
+`
+
+func (d *Database) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error {
+	_, err := d.DB.NamedExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, token_version, mfa, expires_at)
+		VALUES (:id, :user_id, :family_id, :token_hash, :token_version, :mfa, :expires_at)
+	`, t)
+	if err != nil {
+		return fmt.Errorf("create refresh token: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	err := d.DB.GetContext(ctx, &t, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1`, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get refresh token: %w", err)
+	}
+	return &t, nil
+}
+
+// RotateRefreshToken гасит токен hash и выдаёт вместо него next в той же цепочке.
+// currentVersion — актуальная версия токенов пользователя из сервиса users.
+func (d *Database) RotateRefreshToken(ctx context.Context, hash string, currentVersion int, next *models.RefreshToken) error {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var old models.RefreshToken
+	err = tx.GetContext(ctx, &old, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("lock refresh token: %w", err)
+	}
+
+	var reject error
+	switch {
+	case old.RevokedAt != nil:
+		reject = ErrRefreshTokenReused
+	case old.TokenVersion != currentVersion:
+		reject = ErrRefreshTokenStale
+	case old.ExpiresAt.Before(time.Now()):
+		return ErrRefreshTokenExpired
+	}
+	if reject != nil {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE refresh_tokens SET revoked_at = NOW()
+			WHERE family_id = $1 AND revoked_at IS NULL
+		`, old.FamilyID); err != nil {
+			return fmt.Errorf("revoke token family: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit tx: %w", err)
+		}
+		return reject
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $2 WHERE id = $1
+	`, old.ID, next.ID); err != nil {
+		return fmt.Errorf("revoke refresh token: %w", err)
+	}
+
+	next.UserID = old.UserID
+	next.FamilyID = old.FamilyID
+	next.MFA = old.MFA
+	if _, err := tx.NamedExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, token_version, mfa, expires_at)
+		VALUES (:id, :user_id, :family_id, :token_hash, :token_version, :mfa, :expires_at)
+	`, next); err != nil {
+		return fmt.Errorf("create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// RevokeRefreshToken отзывает цепочку, которой принадлежит токен (выход с одного устройства)
+func (d *Database) RevokeRefreshToken(ctx context.Context, hash string) error {
+	_, err := d.DB.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE revoked_at IS NULL
+		  AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+	`, hash)
+	if err != nil {
+		return fmt.Errorf("revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	_, err := d.DB.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("revoke user refresh tokens: %w", err)
+	}
+	return nil
+}
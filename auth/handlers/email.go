@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"auth/database"
+	"auth/mail"
+	"auth/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Повторное письмо того же назначения не чаще этого
+const emailResendInterval = time.Minute
+
+// errUserChanged — пользователь удалён или сменил почту после отправки письма
+var errUserChanged = errors.New("user changed since the token was issued")
+
+// Ответ на запросы по почте не зависит от того, есть ли такой пользователь
+const emailSentMessage = "If the account exists, an email has been sent"
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req models.TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	err := h.db.ConsumeEmailToken(c.Request.Context(), hashToken(req.Token), models.PurposeVerifyEmail, func(t *models.EmailToken) error {
+		status, err := h.callUsers(c.Request.Context(), http.MethodPost, "/"+t.UserID+"/verify-email", gin.H{"email": t.Email}, nil)
+		if err != nil {
+			return err
+		}
+		if status == http.StatusNotFound {
+			return errUserChanged
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("users service responded with %d", status)
+		}
+		return nil
+	})
+	if errors.Is(err, database.ErrEmailTokenInvalid) || errors.Is(err, errUserChanged) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to verify email: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	user, err := h.findUserByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		h.logger.Errorf("failed to find user by email: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
+		return
+	}
+	if user != nil && !user.EmailVerified {
+		if err := h.sendEmailToken(c.Request.Context(), user, models.PurposeVerifyEmail); err != nil {
+			h.logger.Errorf("failed to send verification email to user %s: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to send email"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": emailSentMessage})
+}
+
+// ForgotPassword отправляет ссылку для сброса пароля
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	user, err := h.findUserByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		h.logger.Errorf("failed to find user by email: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
+		return
+	}
+	if user != nil {
+		if err := h.sendEmailToken(c.Request.Context(), user, models.PurposeResetPassword); err != nil {
+			h.logger.Errorf("failed to send password reset email to user %s: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to send email"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": emailSentMessage})
+}
+
+// ResetPassword задаёт новый пароль по токену из письма и завершает все сессии
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if err := h.cfg.PasswordPolicy.Validate(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var userID string
+	err := h.db.ConsumeEmailToken(ctx, hashToken(req.Token), models.PurposeResetPassword, func(t *models.EmailToken) error {
+		user, err := h.fetchUser(ctx, t.UserID)
+		if err != nil {
+			return err
+		}
+		if user == nil || user.Email != t.Email {
+			return errUserChanged
+		}
+		userID = t.UserID
+		return h.setPassword(ctx, userID, req.Password)
+	})
+	if errors.Is(err, database.ErrEmailTokenInvalid) || errors.Is(err, errUserChanged) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to reset password: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to reset password"})
+		return
+	}
+
+	h.logger.Infof("password reset for user %s", userID)
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// sendEmailToken выпускает одноразовый токен и отправляет письмо со ссылкой
+func (h *AuthHandler) sendEmailToken(ctx context.Context, user *models.UserResponse, purpose string) error {
+	recent, err := h.db.EmailTokenSentWithin(ctx, user.ID, purpose, emailResendInterval)
+	if err != nil {
+		return err
+	}
+	if recent {
+		return nil
+	}
+
+	raw, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	ttl, page, subject, text := h.cfg.VerifyTokenTTL, "/verify-email", "Подтверждение почты",
+		"Чтобы подтвердить почту, перейдите по ссылке:"
+	if purpose == models.PurposeResetPassword {
+		ttl, page, subject, text = h.cfg.ResetTokenTTL, "/reset-password", "Сброс пароля",
+			"Чтобы задать новый пароль, перейдите по ссылке. Если вы не запрашивали сброс, просто проигнорируйте письмо."
+	}
+
+	err = h.db.CreateEmailToken(ctx, &models.EmailToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Email:     user.Email,
+		Purpose:   purpose,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := h.cfg.AppURL + page + "?token=" + url.QueryEscape(raw)
+	return h.mail.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("Здравствуйте, %s!\n\n%s\n%s\n\nСсылка действует %s.\n", user.Username, text, link, ttl),
+	})
+}
+
+// findUserByEmail возвращает пользователя по почте; nil — такого нет
+func (h *AuthHandler) findUserByEmail(ctx context.Context, email string) (*models.UserResponse, error) {
+	var user models.UserResponse
+	status, err := h.callUsers(ctx, http.MethodGet, "/by-email?email="+url.QueryEscape(email), nil, &user)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("users service responded with %d", status)
+	}
+	return &user, nil
+}
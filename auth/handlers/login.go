@@ -1,8 +1,6 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/json"
 	"net/http"
 
 	"auth/models"
@@ -18,52 +16,69 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	body, err := json.Marshal(req)
-	if err != nil {
-		h.logger.Errorf("failed to marshal login request: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to marshal request"})
+	if h.rejectThrottled(c, req.Email) {
 		return
 	}
 
-	resp, err := http.Post(h.cfg.UsersURL+"/auth", "application/json", bytes.NewBuffer(body))
+	ctx := c.Request.Context()
+	found, err := h.findUserByEmail(ctx, req.Email)
 	if err != nil {
-		h.logger.Errorf("failed to contact users service: %v", err)
+		h.logger.Errorf("failed to find user by email: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
 		return
 	}
-	defer resp.Body.Close()
+	ok, err := h.checkPassword(ctx, found, req.Password)
+	if err != nil {
+		h.logger.Errorf("failed to check password: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to log in"})
+		return
+	}
+	if !ok {
+		h.loginFailed(c, req.Email)
+		h.logger.Warn("login failed: invalid credentials")
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid credentials"})
+		return
+	}
+	user := *found
 
-	if resp.StatusCode != http.StatusOK {
-		var errResp models.ErrorResponse
-		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
-			h.logger.Warn("invalid credentials: unable to decode error response")
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid credentials"})
-			return
-		}
-		h.logger.Warnf("login failed: %s", errResp.Error)
-		c.JSON(resp.StatusCode, errResp)
+	mfa, err := h.mfaEnabled(ctx, user.ID)
+	if err != nil {
+		h.logger.Errorf("failed to get mfa for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to log in"})
 		return
 	}
+	// Со включённым вторым фактором счётчик неудач сбрасывается только после кода,
+	// иначе подбор кода можно было бы продолжать, раз за разом вводя пароль
+	if mfa {
+		h.recordLoginAttempt(c, req.Email, &user.ID, models.LoginMFARequired)
+	} else {
+		h.loginSucceeded(c, req.Email, user.ID)
+	}
 
-	var user models.UserResponse
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		h.logger.Errorf("failed to decode user response: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Invalid user response"})
+	if h.cfg.RequireEmailVerification && !user.EmailVerified {
+		h.logger.Warnf("login rejected for user %s: email is not verified", user.ID)
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Email is not verified"})
 		return
 	}
-	if user.ID == "" {
-		h.logger.Error("empty user ID in response from users service")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "User ID is missing in response"})
+
+	if mfa {
+		challenge, err := h.newMFAChallenge(ctx, &user)
+		if err != nil {
+			h.logger.Errorf("failed to create mfa challenge: %v", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to log in"})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
 		return
 	}
 
-	token, err := GenerateJWT(user.ID, h.cfg.JWTSecret)
+	tokens, err := h.issueTokens(ctx, &user, false)
 	if err != nil {
-		h.logger.Errorf("failed to generate JWT: %v", err)
+		h.logger.Errorf("failed to issue tokens: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
 		return
 	}
 
 	h.logger.Infof("user %s logged in successfully", user.ID)
-	c.JSON(http.StatusOK, models.AuthResponse{Token: token})
+	c.JSON(http.StatusOK, tokens)
 }
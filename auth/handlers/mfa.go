@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"auth/database"
+	"auth/models"
+	"auth/totp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// За это время нужно ввести код после пароля
+	mfaChallengeTTL = 5 * time.Minute
+	// Попыток ввести код на один вход; дальше — снова пароль
+	mfaMaxAttempts    = 5
+	recoveryCodeCount = 10
+)
+
+// MFAStatus сообщает, включена ли двухфакторная аутентификация
+func (h *AuthHandler) MFAStatus(c *gin.Context) {
+	claims, _, ok := h.parseAccessToken(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	enabled, err := h.mfaEnabled(ctx, claims.UserID)
+	if err != nil {
+		h.logger.Errorf("failed to get mfa for user %s: %v", claims.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get two-factor status"})
+		return
+	}
+	resp := models.MFAStatusResponse{Enabled: enabled}
+	if enabled {
+		if resp.RecoveryCodesLeft, err = h.db.CountRecoveryCodes(ctx, claims.UserID); err != nil {
+			h.logger.Errorf("failed to count recovery codes for user %s: %v", claims.UserID, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get two-factor status"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// MFAEnroll по паролю выдаёт новый секрет; двухфакторная аутентификация
+// включится после MFAConfirm
+func (h *AuthHandler) MFAEnroll(c *gin.Context) {
+	_, user, ok := h.parseAccessToken(c)
+	if !ok {
+		return
+	}
+	var req models.MFAEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if !h.reauthenticate(c, user, req.Password) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		h.logger.Errorf("failed to generate totp secret: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to start enrollment"})
+		return
+	}
+	started, err := h.db.StartMFAEnrollment(ctx, user.ID, secret)
+	if err != nil {
+		h.logger.Errorf("failed to start mfa enrollment for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to start enrollment"})
+		return
+	}
+	if !started {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(h.cfg.MFAIssuer, user.Email, secret),
+	})
+}
+
+// MFAConfirm включает двухфакторную аутентификацию по первому коду из приложения
+// и возвращает коды восстановления. Они показываются только один раз.
+func (h *AuthHandler) MFAConfirm(c *gin.Context) {
+	claims, _, ok := h.parseAccessToken(c)
+	if !ok {
+		return
+	}
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	m, err := h.db.GetMFA(ctx, claims.UserID)
+	if err != nil {
+		h.logger.Errorf("failed to get mfa for user %s: %v", claims.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to enable two-factor authentication"})
+		return
+	}
+	if m == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Enrollment has not been started"})
+		return
+	}
+	if m.EnabledAt != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+
+	step, ok := totp.Validate(m.Secret, normalizeCode(req.Code), time.Now(), m.LastUsedStep)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		h.logger.Errorf("failed to generate recovery codes: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to enable two-factor authentication"})
+		return
+	}
+	if err := h.db.EnableMFA(ctx, claims.UserID, step, hashes); err != nil {
+		h.logger.Errorf("failed to enable mfa for user %s: %v", claims.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to enable two-factor authentication"})
+		return
+	}
+
+	h.logger.Infof("two-factor authentication enabled for user %s", claims.UserID)
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// MFADisable отключает двухфакторную аутентификацию по паролю и коду
+// из приложения или коду восстановления
+func (h *AuthHandler) MFADisable(c *gin.Context) {
+	_, user, ok := h.parseAccessToken(c)
+	if !ok {
+		return
+	}
+	var req models.MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if !h.reauthenticate(c, user, req.Password) {
+		return
+	}
+	kind, ok := h.checkMFACode(c, user, req.Code)
+	if !ok {
+		return
+	}
+
+	if _, err := h.db.DisableMFA(c.Request.Context(), user.ID); err != nil {
+		h.logger.Errorf("failed to disable mfa for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to disable two-factor authentication"})
+		return
+	}
+
+	h.logger.Infof("two-factor authentication disabled for user %s (%s)", user.ID, kind)
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// MFARecoveryCodes заменяет коды восстановления новыми
+func (h *AuthHandler) MFARecoveryCodes(c *gin.Context) {
+	_, user, ok := h.parseAccessToken(c)
+	if !ok {
+		return
+	}
+	var req models.MFARecoveryCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if !h.reauthenticate(c, user, req.Password) {
+		return
+	}
+	if _, ok := h.checkMFACode(c, user, req.Code); !ok {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		h.logger.Errorf("failed to generate recovery codes: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate recovery codes"})
+		return
+	}
+	if err := h.db.ReplaceRecoveryCodes(c.Request.Context(), user.ID, hashes); err != nil {
+		h.logger.Errorf("failed to replace recovery codes for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate recovery codes"})
+		return
+	}
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// MFAReset отключает двухфакторную аутентификацию пользователю, потерявшему
+// и приложение, и коды восстановления. Только для администраторов.
+func (h *AuthHandler) MFAReset(c *gin.Context) {
+	admin, ok := h.requireAdmin(c)
+	if !ok {
+		return
+	}
+
+	userID := c.Param("user_id")
+	ctx := c.Request.Context()
+	disabled, err := h.db.DisableMFA(ctx, userID)
+	if err != nil {
+		h.logger.Errorf("failed to reset mfa for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to reset two-factor authentication"})
+		return
+	}
+	if !disabled {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Two-factor authentication is not enabled"})
+		return
+	}
+	// Сессии, подтверждённые старым вторым фактором, завершаем
+	if err := h.db.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		h.logger.Errorf("failed to revoke refresh tokens for user %s: %v", userID, err)
+	}
+
+	h.logger.Warnf("two-factor authentication for user %s reset by admin %s", userID, admin.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// LoginMFA — второй шаг входа: обменивает вызов и код на токены
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req models.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	hash := hashToken(req.ChallengeToken)
+	ch, err := h.db.GetMFAChallenge(ctx, hash, mfaMaxAttempts)
+	if errors.Is(err, database.ErrMFAChallengeInvalid) {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or expired challenge, log in again"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to get mfa challenge: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to verify code"})
+		return
+	}
+
+	if h.rejectThrottled(c, ch.Email) {
+		return
+	}
+
+	ok, err := h.verifySecondFactor(ctx, ch.UserID, req.Code)
+	if err != nil {
+		h.logger.Errorf("failed to verify second factor for user %s: %v", ch.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to verify code"})
+		return
+	}
+	if !ok {
+		h.loginFailed(c, ch.Email)
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid code"})
+		return
+	}
+
+	if err := h.db.DeleteMFAChallenge(ctx, hash); err != nil {
+		h.logger.Errorf("failed to delete mfa challenge: %v", err)
+	}
+
+	user, err := h.fetchUser(ctx, ch.UserID)
+	if err != nil {
+		h.logger.Errorf("failed to fetch user %s: %v", ch.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or expired challenge, log in again"})
+		return
+	}
+
+	h.loginSucceeded(c, ch.Email, user.ID)
+
+	tokens, err := h.issueTokens(ctx, user, true)
+	if err != nil {
+		h.logger.Errorf("failed to issue tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+
+	h.logger.Infof("user %s logged in with second factor", user.ID)
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) mfaEnabled(ctx context.Context, userID string) (bool, error) {
+	m, err := h.db.GetMFA(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return m != nil && m.EnabledAt != nil, nil
+}
+
+// newMFAChallenge выпускает одноразовый токен второго шага входа
+func (h *AuthHandler) newMFAChallenge(ctx context.Context, user *models.UserResponse) (*models.MFAChallengeResponse, error) {
+	raw, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = h.db.CreateMFAChallenge(ctx, &models.MFAChallenge{
+		TokenHash: hashToken(raw),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(mfaChallengeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.MFAChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: raw,
+		ExpiresIn:      int64(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// verifySecondFactor принимает код из приложения или неиспользованный код восстановления
+func (h *AuthHandler) verifySecondFactor(ctx context.Context, userID, code string) (bool, error) {
+	m, err := h.db.GetMFA(ctx, userID)
+	if err != nil || m == nil || m.EnabledAt == nil {
+		return false, err
+	}
+
+	code = normalizeCode(code)
+	if step, ok := totp.Validate(m.Secret, code, time.Now(), m.LastUsedStep); ok {
+		// Условное обновление: параллельный запрос с тем же кодом не пройдёт
+		return h.db.UseTOTPStep(ctx, userID, step)
+	}
+	return h.db.UseRecoveryCode(ctx, userID, hashToken(code))
+}
+
+// checkMFACode проверяет второй фактор и возвращает вид принятого кода.
+// Неверный код учитывается счётчиками входа, как и на втором шаге входа.
+// При ошибке ответ уже отправлен.
+func (h *AuthHandler) checkMFACode(c *gin.Context, user *models.UserResponse, code string) (string, bool) {
+	if h.rejectThrottled(c, user.Email) {
+		return "", false
+	}
+
+	ok, err := h.verifySecondFactor(c.Request.Context(), user.ID, code)
+	if err != nil {
+		h.logger.Errorf("failed to verify second factor for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to verify code"})
+		return "", false
+	}
+	if !ok {
+		h.loginFailed(c, user.Email)
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid code"})
+		return "", false
+	}
+
+	if len(normalizeCode(code)) != totp.Digits {
+		return "recovery code", true
+	}
+	return "totp", true
+}
+
+// reauthenticate требует повторно ввести пароль перед изменением настроек входа:
+// одного access-токена для этого мало. Неверный пароль учитывается счётчиками
+// входа, иначе с украденным токеном пароль можно было бы подбирать без ограничений.
+func (h *AuthHandler) reauthenticate(c *gin.Context, user *models.UserResponse, password string) bool {
+	if h.rejectThrottled(c, user.Email) {
+		return false
+	}
+
+	ok, err := h.checkPassword(c.Request.Context(), user, password)
+	if err != nil {
+		h.logger.Errorf("failed to check password for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check password"})
+		return false
+	}
+	if !ok {
+		h.loginFailed(c, user.Email)
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Password is incorrect"})
+		return false
+	}
+	return true
+}
+
+// requireAdmin пропускает только действующий токен администратора; роль
+// берётся из сервиса users, а не из токена
+func (h *AuthHandler) requireAdmin(c *gin.Context) (*AccessClaims, bool) {
+	claims, user, ok := h.parseAccessToken(c)
+	if !ok {
+		return nil, false
+	}
+	if user.Role != "admin" {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Insufficient role"})
+		return nil, false
+	}
+	if h.cfg.RequireStaffMFA && !claims.MFA {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Two-factor authentication required"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// newRecoveryCodes возвращает коды для показа пользователю и их хэши для хранения
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(secret[:10])
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode убирает пробелы и дефисы, с которыми пользователи вводят коды
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"auth/database"
+	"auth/models"
+	"auth/oidc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// За это время пользователь должен вернуться от провайдера
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	// Провайдер не подтвердил почту — связывать по ней аккаунты нельзя
+	errOIDCEmailUnverified = errors.New("provider email is not verified")
+	// Аккаунт с этой почтой есть, но почта в нём не подтверждена: его мог
+	// зарегистрировать кто угодно, поэтому привязывать к нему вход нельзя
+	errOIDCAccountUnverified = errors.New("existing account email is not verified")
+)
+
+// OIDCProviders возвращает имена настроенных провайдеров для кнопок входа
+func (h *AuthHandler) OIDCProviders(c *gin.Context) {
+	names := make([]string, 0, len(h.oidc))
+	for name := range h.oidc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.JSON(http.StatusOK, gin.H{"providers": names})
+}
+
+// OIDCLogin перенаправляет на страницу входа провайдера
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	provider, ok := h.oidc[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Unknown provider"})
+		return
+	}
+
+	state, err1 := randomToken()
+	nonce, err2 := randomToken()
+	verifier, err3 := randomToken()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		h.logger.Errorf("failed to generate oidc state: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to start login"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		h.logger.Errorf("failed to build %s authorization url: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{Error: "Identity provider unavailable"})
+		return
+	}
+
+	err = h.db.CreateOIDCState(ctx, &models.OIDCState{
+		StateHash:    hashToken(state),
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	})
+	if err != nil {
+		h.logger.Errorf("failed to save oidc state: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to start login"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback завершает вход: обменивает код на ID-токен, находит или создаёт
+// пользователя и возвращает фронтенду токены во фрагменте APP_URL/oauth/callback
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	provider, ok := h.oidc[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Unknown provider"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	state, err := h.db.ConsumeOIDCState(ctx, hashToken(c.Query("state")), provider.Name())
+	if errors.Is(err, database.ErrOIDCStateInvalid) {
+		h.oidcRedirect(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to consume oidc state: %v", err)
+		h.oidcRedirect(c, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	// Пользователь отказался от входа или провайдер вернул ошибку
+	if e := c.Query("error"); e != "" {
+		h.logger.Warnf("%s login failed: %s %s", provider.Name(), e, c.Query("error_description"))
+		h.oidcRedirect(c, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	claims, err := provider.Exchange(ctx, c.Query("code"), state.CodeVerifier, state.Nonce)
+	if err != nil {
+		h.logger.Errorf("%s code exchange failed: %v", provider.Name(), err)
+		h.oidcRedirect(c, url.Values{"error": {"exchange_failed"}})
+		return
+	}
+
+	user, err := h.oidcUser(ctx, provider.Name(), claims)
+	switch {
+	case errors.Is(err, errOIDCEmailUnverified):
+		h.oidcRedirect(c, url.Values{"error": {"email_not_verified"}})
+		return
+	case errors.Is(err, errOIDCAccountUnverified):
+		h.oidcRedirect(c, url.Values{"error": {"account_not_verified"}})
+		return
+	case err != nil:
+		h.logger.Errorf("failed to resolve %s user %s: %v", provider.Name(), claims.Subject, err)
+		h.oidcRedirect(c, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	mfa, err := h.mfaEnabled(ctx, user.ID)
+	if err != nil {
+		h.logger.Errorf("failed to get mfa for user %s: %v", user.ID, err)
+		h.oidcRedirect(c, url.Values{"error": {"server_error"}})
+		return
+	}
+	// Вход через провайдера не заменяет второй фактор: фронтенд завершает его через /login/2fa
+	if mfa {
+		challenge, err := h.newMFAChallenge(ctx, user)
+		if err != nil {
+			h.logger.Errorf("failed to create mfa challenge: %v", err)
+			h.oidcRedirect(c, url.Values{"error": {"server_error"}})
+			return
+		}
+		h.oidcRedirect(c, url.Values{
+			"mfa_required":    {"true"},
+			"challenge_token": {challenge.ChallengeToken},
+			"expires_in":      {strconv.FormatInt(challenge.ExpiresIn, 10)},
+		})
+		return
+	}
+
+	tokens, err := h.issueTokens(ctx, user, false)
+	if err != nil {
+		h.logger.Errorf("failed to issue tokens: %v", err)
+		h.oidcRedirect(c, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	h.logger.Infof("user %s logged in via %s", user.ID, provider.Name())
+	h.oidcRedirect(c, url.Values{
+		"token":         {tokens.Token},
+		"refresh_token": {tokens.RefreshToken},
+		"expires_in":    {strconv.FormatInt(tokens.ExpiresIn, 10)},
+	})
+}
+
+// oidcRedirect возвращает пользователя на фронтенд. Данные передаются во фрагменте,
+// чтобы токены не попадали в логи серверов и заголовок Referer.
+func (h *AuthHandler) oidcRedirect(c *gin.Context, values url.Values) {
+	c.Redirect(http.StatusFound, h.cfg.AppURL+"/oauth/callback#"+values.Encode())
+}
+
+// oidcUser находит пользователя по привязке, иначе связывает с аккаунтом
+// с той же подтверждённой почтой или создаёт новый
+func (h *AuthHandler) oidcUser(ctx context.Context, provider string, claims *oidc.Claims) (*models.UserResponse, error) {
+	identity, err := h.db.GetOIDCIdentity(ctx, provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := h.fetchUser(ctx, identity.UserID)
+		if err != nil || user != nil {
+			return user, err
+		}
+		// Привязанный пользователь удалён: привяжем заново
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errOIDCEmailUnverified
+	}
+
+	user, err := h.findUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && !user.EmailVerified {
+		return nil, errOIDCAccountUnverified
+	}
+	if user == nil {
+		if user, err = h.createOIDCUser(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	err = h.db.LinkOIDCIdentity(ctx, &models.OIDCIdentity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		UserID:   user.ID,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createOIDCUser регистрирует пользователя через сервис users так же, как Register,
+// но без пароля: задать его можно через сброс пароля.
+func (h *AuthHandler) createOIDCUser(ctx context.Context, claims *oidc.Claims) (*models.UserResponse, error) {
+	username, err := h.pickUsername(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var created models.UserResponse
+	status, err := h.callUsers(ctx, http.MethodPost, "/", models.UserCreateRequest{
+		Username: username,
+		Email:    claims.Email,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusCreated || created.ID == "" {
+		return nil, fmt.Errorf("create user: users service responded with %d", status)
+	}
+
+	// Почту уже подтвердил провайдер
+	status, err = h.callUsers(ctx, http.MethodPost, "/"+created.ID+"/verify-email", gin.H{"email": created.Email}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("verify email: users service responded with %d", status)
+	}
+	created.EmailVerified = true
+
+	h.logger.Infof("user registered via oidc: id=%s, email=%s", created.ID, created.Email)
+	return &created, nil
+}
+
+// pickUsername подбирает свободный никнейм по данным провайдера
+func (h *AuthHandler) pickUsername(ctx context.Context, claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, base)
+	if r := []rune(base); len(r) > 20 {
+		base = string(r[:20])
+	}
+	if len([]rune(base)) < 5 {
+		base += "_player"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		status, err := h.callUsers(ctx, http.MethodGet, "/check-username?username="+url.QueryEscape(candidate), nil, nil)
+		if err != nil {
+			return "", err
+		}
+		if status != http.StatusConflict {
+			return candidate, nil
+		}
+		suffix, err := randomToken()
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + strings.ToLower(suffix[:4])
+	}
+	return "", fmt.Errorf("no free username for %q", base)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"auth/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePassword меняет пароль по текущему и завершает все сессии, кроме новой
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	claims, user, ok := h.parseAccessToken(c)
+	if !ok {
+		return
+	}
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+	if err := h.cfg.PasswordPolicy.Validate(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ok, err := h.checkPassword(ctx, user, req.CurrentPassword)
+	if err != nil {
+		h.logger.Errorf("failed to check password for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to change password"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Current password is incorrect"})
+		return
+	}
+
+	if err := h.setPassword(ctx, user.ID, req.NewPassword); err != nil {
+		h.logger.Errorf("failed to change password for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to change password"})
+		return
+	}
+
+	// Версия токенов повышена: выдаём новую пару взамен отозванной
+	if user, err = h.fetchUser(ctx, user.ID); err != nil || user == nil {
+		h.logger.Errorf("failed to fetch user %s after password change: %v", claims.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
+		return
+	}
+	tokens, err := h.issueTokens(ctx, user, claims.MFA)
+	if err != nil {
+		h.logger.Errorf("failed to issue tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+
+	h.logger.Infof("password changed for user %s", user.ID)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// checkPassword сверяет пароль с хранимым хэшем и при необходимости пересчитывает хэш.
+// user может быть nil: тогда хэш всё равно вычисляется, чтобы по времени ответа
+// нельзя было узнать, зарегистрирована ли почта.
+func (h *AuthHandler) checkPassword(ctx context.Context, user *models.UserResponse, password string) (bool, error) {
+	hash := ""
+	if user != nil {
+		var err error
+		if hash, err = h.db.GetPasswordHash(ctx, user.ID); err != nil {
+			return false, err
+		}
+		if hash == "" {
+			if hash, err = h.importLegacyPassword(ctx, user.ID); err != nil {
+				return false, err
+			}
+		}
+	}
+	if hash == "" {
+		_, _, _ = h.cfg.PasswordHasher.Verify(h.dummyHash(), password)
+		return false, nil
+	}
+
+	ok, rehash, err := h.cfg.PasswordHasher.Verify(hash, password)
+	if err != nil || !ok {
+		return false, err
+	}
+	if rehash {
+		if newHash, err := h.cfg.PasswordHasher.Hash(password); err != nil {
+			h.logger.Errorf("failed to rehash password for user %s: %v", user.ID, err)
+		} else if err := h.db.SetPasswordHash(ctx, user.ID, newHash); err != nil {
+			h.logger.Errorf("failed to store rehashed password for user %s: %v", user.ID, err)
+		} else {
+			h.logger.Infof("password hash upgraded for user %s", user.ID)
+		}
+	}
+	return true, nil
+}
+
+// setPassword сохраняет новый пароль и отзывает все токены пользователя
+func (h *AuthHandler) setPassword(ctx context.Context, userID, password string) error {
+	hash, err := h.cfg.PasswordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := h.db.SetPasswordHash(ctx, userID, hash); err != nil {
+		return err
+	}
+	if err := h.revokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	return h.db.RevokeUserRefreshTokens(ctx, userID)
+}
+
+// importLegacyPassword переносит хэш, который раньше хранил сервис users.
+// Пустая строка — переносить нечего.
+func (h *AuthHandler) importLegacyPassword(ctx context.Context, userID string) (string, error) {
+	var legacy models.LegacyPasswordResponse
+	status, err := h.callUsers(ctx, http.MethodGet, "/"+userID+"/legacy-password", nil, &legacy)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusNotFound {
+		return "", nil
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("users service responded with %d", status)
+	}
+
+	if err := h.db.SetPasswordHash(ctx, userID, legacy.PasswordHash); err != nil {
+		return "", err
+	}
+	if status, err := h.callUsers(ctx, http.MethodDelete, "/"+userID+"/legacy-password", nil, nil); err != nil || status != http.StatusOK {
+		h.logger.Errorf("failed to clear legacy password of user %s: status %d, %v", userID, status, err)
+	}
+	h.logger.Infof("password of user %s moved from users service", userID)
+	return legacy.PasswordHash, nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+func (h *AuthHandler) dummyHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = h.cfg.PasswordHasher.Hash("dummy-password-1")
+	})
+	return dummyHash
+}
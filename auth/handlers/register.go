@@ -1,14 +1,12 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
 	"net/http"
 
 	"auth/models"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -19,13 +17,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	if msg, ok := h.validateRegisterRequest(&req); !ok {
+	ctx := c.Request.Context()
+	if msg, ok := h.validateRegisterRequest(ctx, &req); !ok {
 		h.logger.Warnf("validation failed: %s", msg)
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: msg})
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := h.cfg.PasswordHasher.Hash(req.Password)
 	if err != nil {
 		h.logger.Errorf("failed to hash password: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to hash password"})
@@ -35,17 +34,9 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	userReq := models.UserCreateRequest{
 		Username: req.Username,
 		Email:    req.Email,
-		Password: string(hashedPassword),
 	}
 
-	body, err := json.Marshal(userReq)
-	if err != nil {
-		h.logger.Errorf("failed to marshal user request: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to marshal user request"})
-		return
-	}
-
-	resp, err := http.Post(h.cfg.UsersURL+"/", "application/json", bytes.NewBuffer(body))
+	resp, err := h.usersRequest(ctx, http.MethodPost, "/", userReq)
 	if err != nil {
 		h.logger.Errorf("failed to contact users service: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
@@ -78,13 +69,34 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	token, err := GenerateJWT(createdUser.ID, h.cfg.JWTSecret)
+	if err := h.db.SetPasswordHash(ctx, createdUser.ID, hashedPassword); err != nil {
+		h.logger.Errorf("failed to store password for user %s: %v", createdUser.ID, err)
+		// Без пароля в аккаунт не войти, а почта и имя останутся занятыми
+		if status, err := h.callUsers(ctx, http.MethodDelete, "/"+createdUser.ID, nil, nil); err != nil || status != http.StatusOK {
+			h.logger.Errorf("failed to delete user %s after failed registration: status %d, %v", createdUser.ID, status, err)
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save password"})
+		return
+	}
+
+	// Письмо не критично для регистрации: его можно запросить повторно
+	if err := h.sendEmailToken(ctx, &createdUser, models.PurposeVerifyEmail); err != nil {
+		h.logger.Errorf("failed to send verification email to user %s: %v", createdUser.ID, err)
+	}
+
+	if h.cfg.RequireEmailVerification {
+		h.logger.Infof("user registered, awaiting email verification: id=%s, email=%s", createdUser.ID, createdUser.Email)
+		c.JSON(http.StatusCreated, gin.H{"message": "Registration successful, please verify your email"})
+		return
+	}
+
+	tokens, err := h.issueTokens(ctx, &createdUser, false)
 	if err != nil {
-		h.logger.Errorf("failed to generate token: %v", err)
+		h.logger.Errorf("failed to issue tokens: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
 		return
 	}
 
 	h.logger.Infof("user registered successfully: id=%s, email=%s", createdUser.ID, createdUser.Email)
-	c.JSON(http.StatusCreated, models.AuthResponse{Token: token})
+	c.JSON(http.StatusCreated, tokens)
 }
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth/models"
+	"auth/throttle"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginKey — счётчик, который проверяется при входе
+type loginKey struct {
+	limiter *throttle.Limiter
+	key     string
+}
+
+func (h *AuthHandler) loginKeys(c *gin.Context, email string) []loginKey {
+	return []loginKey{
+		{h.accountLimiter, "account:" + normalizeEmail(email)},
+		{h.ipLimiter, "ip:" + c.ClientIP()},
+	}
+}
+
+// rejectThrottled отвечает 429, если аккаунт или адрес временно заблокирован.
+// Ошибка хранилища счётчиков не блокирует вход: проверка пароля в checkPassword идёт как обычно.
+func (h *AuthHandler) rejectThrottled(c *gin.Context, email string) bool {
+	var wait time.Duration
+	for _, k := range h.loginKeys(c, email) {
+		w, err := k.limiter.Blocked(c.Request.Context(), k.key)
+		if err != nil {
+			h.logger.Errorf("failed to check login throttle for %s: %v", k.key, err)
+			continue
+		}
+		if w > wait {
+			wait = w
+		}
+	}
+	if wait == 0 {
+		return false
+	}
+
+	h.recordLoginAttempt(c, email, nil, models.LoginThrottled)
+
+	seconds := int64(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.JSON(http.StatusTooManyRequests, models.ThrottledResponse{
+		Error:      "Too many failed login attempts, try again later",
+		RetryAfter: seconds,
+	})
+	return true
+}
+
+// loginFailed учитывает неверный пароль по аккаунту и по адресу
+func (h *AuthHandler) loginFailed(c *gin.Context, email string) {
+	for _, k := range h.loginKeys(c, email) {
+		d, err := k.limiter.Fail(c.Request.Context(), k.key)
+		if err != nil {
+			h.logger.Errorf("failed to record login failure for %s: %v", k.key, err)
+			continue
+		}
+		if d > 0 {
+			h.logger.Warnf("login blocked for %s for %s", k.key, d)
+		}
+	}
+	h.recordLoginAttempt(c, email, nil, models.LoginFailed)
+}
+
+// loginSucceeded сбрасывает счётчик аккаунта. Счётчик IP не сбрасывается,
+// иначе вход в свой аккаунт позволял бы продолжать подбор чужих.
+func (h *AuthHandler) loginSucceeded(c *gin.Context, email, userID string) {
+	key := h.loginKeys(c, email)[0]
+	if err := key.limiter.Reset(c.Request.Context(), key.key); err != nil {
+		h.logger.Errorf("failed to reset login throttle for %s: %v", key.key, err)
+	}
+	h.recordLoginAttempt(c, email, &userID, models.LoginSucceeded)
+}
+
+func (h *AuthHandler) recordLoginAttempt(c *gin.Context, email string, userID *string, result string) {
+	err := h.db.RecordLoginAttempt(c.Request.Context(), &models.LoginAttempt{
+		Email:     normalizeEmail(email),
+		UserID:    userID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Result:    result,
+	})
+	if err != nil {
+		h.logger.Errorf("failed to record login attempt: %v", err)
+	}
+}
+
+// PurgeLoginThrottle удаляет устаревшие счётчики; вызывается периодически из main
+func (h *AuthHandler) PurgeLoginThrottle(ctx context.Context) {
+	for _, l := range []*throttle.Limiter{h.accountLimiter, h.ipLimiter} {
+		if err := l.Purge(ctx); err != nil {
+			h.logger.Errorf("failed to purge login throttle: %v", err)
+		}
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"auth/database"
+	"auth/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// JWKS публикует открытые ключи, которыми шлюз проверяет access-токены
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов; предъявленный токен гасится
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	hash := hashToken(req.RefreshToken)
+
+	stored, err := h.db.GetRefreshToken(ctx, hash)
+	if errors.Is(err, database.ErrRefreshTokenNotFound) {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to get refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
+
+	// Роль и версию токенов берём актуальные, а не из старого токена
+	user, err := h.fetchUser(ctx, stored.UserID)
+	if err != nil {
+		h.logger.Errorf("failed to fetch user %s: %v", stored.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
+		return
+	}
+	if user == nil {
+		if err := h.db.RevokeUserRefreshTokens(ctx, stored.UserID); err != nil {
+			h.logger.Errorf("failed to revoke tokens of deleted user %s: %v", stored.UserID, err)
+		}
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid refresh token"})
+		return
+	}
+
+	raw, next, err := newRefreshToken(user.ID, user.TokenVersion, h.cfg.RefreshTokenTTL)
+	if err != nil {
+		h.logger.Errorf("failed to generate refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
+
+	err = h.db.RotateRefreshToken(ctx, hash, user.TokenVersion, next)
+	switch {
+	case errors.Is(err, database.ErrRefreshTokenReused):
+		h.logger.Warnf("refresh token reuse detected for user %s, session revoked", user.ID)
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Refresh token has been revoked"})
+		return
+	case errors.Is(err, database.ErrRefreshTokenStale):
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Refresh token has been revoked"})
+		return
+	case errors.Is(err, database.ErrRefreshTokenExpired):
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Refresh token has expired"})
+		return
+	case errors.Is(err, database.ErrRefreshTokenNotFound):
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid refresh token"})
+		return
+	case err != nil:
+		h.logger.Errorf("failed to rotate refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
+
+	access, err := h.generateAccessToken(user, next.MFA)
+	if err != nil {
+		h.logger.Errorf("failed to generate JWT: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Token:        access,
+		RefreshToken: raw,
+		ExpiresIn:    int64(h.cfg.AccessTokenTTL.Seconds()),
+	})
+}
+
+// Logout завершает сессию, к которой относится refresh-токен.
+// Выданный access-токен живёт до истечения своего короткого срока.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request"})
+		return
+	}
+
+	if err := h.db.RevokeRefreshToken(c.Request.Context(), hashToken(req.RefreshToken)); err != nil {
+		h.logger.Errorf("failed to revoke refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll завершает все сессии пользователя: отзывает refresh-токены
+// и повышает версию токенов, после чего шлюз отклоняет выданные access-токены
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	claims, _, ok := h.parseAccessToken(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.revokeUserTokens(ctx, claims.UserID); err != nil {
+		h.logger.Errorf("failed to bump token version for user %s: %v", claims.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
+		return
+	}
+	if err := h.db.RevokeUserRefreshTokens(ctx, claims.UserID); err != nil {
+		h.logger.Errorf("failed to revoke refresh tokens for user %s: %v", claims.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to log out"})
+		return
+	}
+
+	h.logger.Infof("user %s logged out of all sessions", claims.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
+// issueTokens выдаёт пару токенов для нового входа; mfa — вход подтверждён вторым фактором
+func (h *AuthHandler) issueTokens(ctx context.Context, user *models.UserResponse, mfa bool) (*models.AuthResponse, error) {
+	access, err := h.generateAccessToken(user, mfa)
+	if err != nil {
+		return nil, fmt.Errorf("generate JWT: %w", err)
+	}
+
+	raw, refresh, err := newRefreshToken(user.ID, user.TokenVersion, h.cfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh.MFA = mfa
+	if err := h.db.CreateRefreshToken(ctx, refresh); err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		Token:        access,
+		RefreshToken: raw,
+		ExpiresIn:    int64(h.cfg.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// fetchUser возвращает роль и версию токенов пользователя; nil — пользователя нет
+func (h *AuthHandler) fetchUser(ctx context.Context, userID string) (*models.UserResponse, error) {
+	var user models.UserResponse
+	status, err := h.callUsers(ctx, http.MethodGet, "/"+userID+"/auth", nil, &user)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("users service responded with %d", status)
+	}
+	return &user, nil
+}
+
+func (h *AuthHandler) revokeUserTokens(ctx context.Context, userID string) error {
+	status, err := h.callUsers(ctx, http.MethodPost, "/"+userID+"/revoke-tokens", nil, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("users service responded with %d", status)
+	}
+	return nil
+}
+
+// callUsers выполняет запрос к сервису users. Тело ответа разбирается в out
+// только при успешном статусе; остальные статусы обрабатывает вызывающий.
+func (h *AuthHandler) callUsers(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	resp, err := h.usersRequest(ctx, method, path, body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 && out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return 0, fmt.Errorf("decode users response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// usersRequest отправляет подписанный запрос к сервису users; тело ответа закрывает вызывающий
+func (h *AuthHandler) usersRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, h.cfg.UsersURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := h.service.SignRequest(req, "users", "", ""); err != nil {
+		return nil, err
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
+// newRefreshToken создаёт непрозрачный refresh-токен новой цепочки; в БД попадает только его хэш
+func newRefreshToken(userID string, version int, ttl time.Duration) (string, *models.RefreshToken, error) {
+	raw, err := randomToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("generate refresh token: %w", err)
+	}
+
+	return raw, &models.RefreshToken{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		FamilyID:     uuid.New().String(),
+		TokenHash:    hashToken(raw),
+		TokenVersion: version,
+		ExpiresAt:    time.Now().Add(ttl),
+	}, nil
+}
+
+// randomToken возвращает 256 случайных бит в URL-безопасной кодировке
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
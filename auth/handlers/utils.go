@@ -1,24 +1,62 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"auth/config"
+	"auth/database"
+	"auth/keys"
+	"auth/mail"
 	"auth/models"
+	"auth/oidc"
+	"auth/throttle"
+	"servicetoken"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
 )
 
 type AuthHandler struct {
 	cfg    *config.Config
+	db     *database.Database
+	keys   *keys.KeySet
+	mail   mail.Sender
 	logger *logrus.Logger
+
+	accountLimiter *throttle.Limiter
+	ipLimiter      *throttle.Limiter
+
+	oidc map[string]*oidc.Provider
+
+	// Подписывает запросы к сервису users
+	service *servicetoken.Signer
 }
 
-func NewAuthHandler(cfg *config.Config, logger *logrus.Logger) *AuthHandler {
-	return &AuthHandler{cfg: cfg, logger: logger}
+func NewAuthHandler(cfg *config.Config, db *database.Database, keySet *keys.KeySet, sender mail.Sender, attempts throttle.Store, logger *logrus.Logger) *AuthHandler {
+	providers := make(map[string]*oidc.Provider, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		providers[p.Name] = oidc.NewProvider(p)
+	}
+
+	return &AuthHandler{
+		cfg:    cfg,
+		db:     db,
+		keys:   keySet,
+		mail:   sender,
+		logger: logger,
+
+		accountLimiter: throttle.NewLimiter(attempts, cfg.AccountThrottle),
+		ipLimiter:      throttle.NewLimiter(attempts, cfg.IPThrottle),
+
+		oidc: providers,
+
+		service: servicetoken.NewSigner("auth", []byte(cfg.ServiceTokenSecret)),
+	}
 }
 
 func isValidEmail(email string) bool {
@@ -36,61 +74,97 @@ func isValidEmail(email string) bool {
 	return true
 }
 
-func isValidPassword(password string) bool {
-	if len(password) < 6 {
-		return false
-	}
-
-	// Проверяем наличие хотя бы одной цифры
-	hasNumber := false
-	for _, char := range password {
-		if char >= '0' && char <= '9' {
-			hasNumber = true
-			break
-		}
-	}
-
-	return hasNumber
-}
-
-func (h *AuthHandler) validateRegisterRequest(req *models.RegisterRequest) (string, bool) {
+func (h *AuthHandler) validateRegisterRequest(ctx context.Context, req *models.RegisterRequest) (string, bool) {
 	if len(req.Username) < 5 {
 		return "Username must be at least 5 characters long", false
 	}
 	if !isValidEmail(req.Email) {
 		return "Invalid email format", false
 	}
-	if !isValidPassword(req.Password) {
-		return "Password must be at least 6 characters long and contain at least one number", false
+	if err := h.cfg.PasswordPolicy.Validate(req.Password); err != nil {
+		return err.Error(), false
 	}
 
 	// Проверка уникальности никнейма
-	resp, err := http.Get(h.cfg.UsersURL + "/check-username?username=" + req.Username)
+	status, err := h.callUsers(ctx, http.MethodGet, "/check-username?username="+url.QueryEscape(req.Username), nil, nil)
 	if err != nil {
 		return "Failed to check username uniqueness", false
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusConflict {
+	if status == http.StatusConflict {
 		return "Username already exists", false
 	}
 
 	// Проверка уникальности email
-	resp, err = http.Get(h.cfg.UsersURL + "/check-email?email=" + req.Email)
+	status, err = h.callUsers(ctx, http.MethodGet, "/check-email?email="+url.QueryEscape(req.Email), nil, nil)
 	if err != nil {
 		return "Failed to check email uniqueness", false
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusConflict {
+	if status == http.StatusConflict {
 		return "Email already exists", false
 	}
 
 	return "", true
 }
 
-func GenerateJWT(userID string, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+// defaultRole — роль для пользователей, созданных до появления ролей
+const defaultRole = "player"
+
+// AccessClaims — содержимое access-токена. Ver сверяется шлюзом с текущей
+// версией токенов пользователя: смена пароля или выход со всех устройств её повышают.
+type AccessClaims struct {
+	UserID       string `json:"user_id"`
+	Role         string `json:"role"`
+	TokenVersion int    `json:"ver"`
+	// Вход подтверждён вторым фактором; шлюз может требовать его для повышенных ролей
+	MFA bool `json:"mfa,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// generateAccessToken подписывает access-токен активным ключом
+func (h *AuthHandler) generateAccessToken(user *models.UserResponse, mfa bool) (string, error) {
+	role := user.Role
+	if role == "" {
+		role = defaultRole
+	}
+	now := time.Now()
+	return h.keys.Sign(AccessClaims{
+		UserID:       user.ID,
+		Role:         role,
+		TokenVersion: user.TokenVersion,
+		MFA:          mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.cfg.AccessTokenTTL)),
+		},
 	})
-	return token.SignedString([]byte(secret))
+}
+
+// parseAccessToken проверяет access-токен из заголовка Authorization и сверяет
+// его версию с текущей версией токенов пользователя в сервисе users: шлюз
+// запросы к auth не проверяет. При ошибке ответ уже отправлен.
+func (h *AuthHandler) parseAccessToken(c *gin.Context) (*AccessClaims, *models.UserResponse, bool) {
+	tokenStr, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or expired token"})
+		return nil, nil, false
+	}
+
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, h.keys.Keyfunc)
+	if err != nil || !token.Valid || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or expired token"})
+		return nil, nil, false
+	}
+
+	user, err := h.fetchUser(c.Request.Context(), claims.UserID)
+	if err != nil {
+		h.logger.Errorf("failed to fetch user %s: %v", claims.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to contact users service"})
+		return nil, nil, false
+	}
+	if user == nil || user.TokenVersion != claims.TokenVersion {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Token has been revoked"})
+		return nil, nil, false
+	}
+	return claims, user, true
 }
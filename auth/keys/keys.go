@@ -0,0 +1,210 @@
+// Пакет keys хранит ключи подписи JWT и публикует их открытые части в формате JWKS.
+//
+// Ключи лежат в каталоге по одному PEM-файлу (PKCS#8, Ed25519 или RSA);
+// имя файла без расширения — kid. Подписывает ключ JWT_ACTIVE_KID, а если он
+// не задан — ключ с наибольшим kid, поэтому удобно называть файлы по дате.
+// Ротация: положить новый ключ, дождаться перечитывания каталога, а старый
+// удалить не раньше, чем истекут подписанные им токены.
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type key struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+type KeySet struct {
+	dir       string
+	activeKID string
+
+	mu     sync.RWMutex
+	keys   map[string]key
+	active key
+}
+
+// Load читает ключи из dir. Если каталог пуст, создаёт первый Ed25519-ключ.
+func Load(dir, activeKID string) (*KeySet, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create keys dir: %w", err)
+	}
+
+	s := &KeySet{dir: dir, activeKID: activeKID}
+	if err := s.Reload(); errors.Is(err, errNoKeys) {
+		if err := generate(dir); err != nil {
+			return nil, err
+		}
+		return s, s.Reload()
+	} else if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+var errNoKeys = errors.New("no signing keys found")
+
+// Reload перечитывает каталог ключей; при ошибке остаётся прежний набор
+func (s *KeySet) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return fmt.Errorf("list keys: %w", err)
+	}
+	if len(paths) == 0 {
+		return errNoKeys
+	}
+	sort.Strings(paths)
+
+	keys := make(map[string]key, len(paths))
+	var latest key
+	for _, p := range paths {
+		k, err := readKey(p)
+		if err != nil {
+			return err
+		}
+		keys[k.id] = k
+		latest = k
+	}
+
+	active := latest
+	if s.activeKID != "" {
+		k, ok := keys[s.activeKID]
+		if !ok {
+			return fmt.Errorf("active key %q not found in %s", s.activeKID, s.dir)
+		}
+		active = k
+	}
+
+	s.mu.Lock()
+	s.keys, s.active = keys, active
+	s.mu.Unlock()
+	return nil
+}
+
+// Sign подписывает claims активным ключом и проставляет kid в заголовок
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	s.mu.RLock()
+	active := s.active
+	s.mu.RUnlock()
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.id
+	return token.SignedString(active.private)
+}
+
+// Keyfunc возвращает открытый ключ для проверки токена по его kid
+func (s *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	s.mu.RLock()
+	k, ok := s.keys[kid]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return k.private.Public(), nil
+}
+
+type JWK struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые части всех ключей, включая ещё не удалённые старые
+func (s *KeySet) JWKS() JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	for _, k := range s.keys {
+		jwk := JWK{KeyID: k.id, Algorithm: k.method.Alg(), Use: "sig"}
+		switch pub := k.private.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType, jwk.Curve = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+func readKey(path string) (key, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return key{}, fmt.Errorf("read key %s: %w", path, err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return key{}, fmt.Errorf("key %s: no PEM block", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return key{}, fmt.Errorf("parse key %s: %w", path, err)
+	}
+
+	k := key{id: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch priv := parsed.(type) {
+	case ed25519.PrivateKey:
+		k.method, k.private = jwt.SigningMethodEdDSA, priv
+	case *rsa.PrivateKey:
+		k.method, k.private = jwt.SigningMethodRS256, priv
+	default:
+		return key{}, fmt.Errorf("key %s: unsupported key type %T", path, parsed)
+	}
+	return k, nil
+}
+
+func generate(dir string) error {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return fmt.Errorf("marshal key: %w", err)
+	}
+
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102T150405")+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write key: %w", err)
+	}
+	return nil
+}
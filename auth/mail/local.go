@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// FileSender складывает письма в каталог в формате .eml
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("create mail dir: %w", err)
+	}
+	name := time.Now().UTC().Format("20060102T150405") + "-" + uuid.New().String() + ".eml"
+	if err := os.WriteFile(filepath.Join(s.dir, name), compose(s.from, msg), 0o644); err != nil {
+		return fmt.Errorf("write mail: %w", err)
+	}
+	return nil
+}
+
+// LogSender только пишет письмо в лог
+type LogSender struct {
+	logger *logrus.Logger
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.logger.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Info("mail:\n" + msg.Body)
+	return nil
+}
@@ -0,0 +1,45 @@
+// Пакет mail отправляет письма пользователям. Реализация выбирается
+// конфигурацией: SMTP для боевого окружения, файл или лог — для разработки и тестов.
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config — параметры отправителя; Driver: smtp, file или log
+type Config struct {
+	Driver string
+	From   string
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+
+	Dir string // для file
+}
+
+func NewSender(cfg Config, logger *logrus.Logger) (Sender, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return &SMTPSender{host: cfg.SMTPHost, port: cfg.SMTPPort, user: cfg.SMTPUser, password: cfg.SMTPPassword, from: cfg.From}, nil
+	case "file":
+		return &FileSender{dir: cfg.Dir, from: cfg.From}, nil
+	case "log", "":
+		return &LogSender{logger: logger}, nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+}
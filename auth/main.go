@@ -2,8 +2,16 @@ package main
 
 import (
 	"auth/config"
+	"auth/database"
 	"auth/handlers"
+	"auth/keys"
+	"auth/mail"
+	"auth/throttle"
+	"context"
 	"log"
+	"servicetoken"
+	"servicetoken/guard"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -18,15 +26,81 @@ func main() {
 
 	logger := logrus.New()
 
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		logger.Fatalf("failed to init database: %v", err)
+	}
+
+	keySet, err := keys.Load(cfg.JWTKeysDir, cfg.JWTActiveKID)
+	if err != nil {
+		logger.Fatalf("failed to load signing keys: %v", err)
+	}
+
+	// Перечитываем каталог ключей, чтобы ротация не требовала перезапуска
+	go func() {
+		for range time.Tick(cfg.KeysReloadEvery) {
+			if err := keySet.Reload(); err != nil {
+				logger.Errorf("failed to reload signing keys: %v", err)
+			}
+		}
+	}()
+
+	sender, err := mail.NewSender(cfg.Mail, logger)
+	if err != nil {
+		logger.Fatalf("failed to init mail sender: %v", err)
+	}
+
+	var attempts throttle.Store = db
+	if cfg.ThrottleStore == "memory" {
+		attempts = throttle.NewMemoryStore()
+	}
+
 	// Инициализация обработчика
-	authHandler := handlers.NewAuthHandler(cfg, logger)
+	authHandler := handlers.NewAuthHandler(cfg, db, keySet, sender, attempts, logger)
+
+	go func() {
+		for range time.Tick(time.Hour) {
+			authHandler.PurgeLoginThrottle(context.Background())
+			if err := db.PurgeOIDCStates(context.Background(), time.Now()); err != nil {
+				logger.Errorf("failed to purge oidc states: %v", err)
+			}
+			if err := db.PurgeMFAChallenges(context.Background(), time.Now()); err != nil {
+				logger.Errorf("failed to purge mfa challenges: %v", err)
+			}
+		}
+	}()
 
 	// Инициализация роутера
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Снаружи сервис доступен только через шлюз
+	router.Use(guard.ServiceAuth(servicetoken.NewVerifier("auth", []byte(cfg.ServiceTokenSecret), "gateway")))
 
 	// Маршруты
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
 	router.POST("/register", authHandler.Register)
 	router.POST("/login", authHandler.Login)
+	router.POST("/login/2fa", authHandler.LoginMFA)
+	router.POST("/refresh", authHandler.Refresh)
+	router.POST("/logout", authHandler.Logout)
+	router.POST("/logout-all", authHandler.LogoutAll)
+	router.POST("/verify-email", authHandler.VerifyEmail)
+	router.POST("/verify-email/resend", authHandler.ResendVerification)
+	router.POST("/password/forgot", authHandler.ForgotPassword)
+	router.POST("/password/reset", authHandler.ResetPassword)
+	router.POST("/password/change", authHandler.ChangePassword)
+	router.GET("/2fa", authHandler.MFAStatus)
+	router.POST("/2fa/enroll", authHandler.MFAEnroll)
+	router.POST("/2fa/confirm", authHandler.MFAConfirm)
+	router.POST("/2fa/disable", authHandler.MFADisable)
+	router.POST("/2fa/recovery-codes", authHandler.MFARecoveryCodes)
+	router.POST("/2fa/reset/:user_id", authHandler.MFAReset)
+	router.GET("/oidc/providers", authHandler.OIDCProviders)
+	router.GET("/oidc/:provider/login", authHandler.OIDCLogin)
+	router.GET("/oidc/:provider/callback", authHandler.OIDCCallback)
 
 	// Запуск
 	logger.Infof("Server starting on port %s", cfg.ServerPort)
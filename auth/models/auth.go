@@ -3,17 +3,24 @@ package models
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 type UserResponse struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+	Role     string `json:"role"`
+
+	EmailVerified bool `json:"email_verified"`
+
+	TokenVersion int `json:"token_version"`
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"` // access-токен
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // время жизни access-токена в секундах
 }
 
 type ErrorResponse struct {
@@ -23,10 +30,19 @@ type ErrorResponse struct {
 type UserCreateRequest struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
-	Password string `json:"password"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// LegacyPasswordResponse — хэш пароля, оставшийся в сервисе users с тех пор, как он хранил пароли
+type LegacyPasswordResponse struct {
+	PasswordHash string `json:"password_hash"`
 }
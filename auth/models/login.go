@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ThrottleEntry — счётчик неудачных входов по ключу (аккаунт или IP)
+type ThrottleEntry struct {
+	Key           string     `db:"key"`
+	Failures      int        `db:"failures"`
+	LastFailureAt time.Time  `db:"last_failure_at"`
+	BlockedUntil  *time.Time `db:"blocked_until"`
+}
+
+// Исход попытки входа в журнале
+const (
+	LoginSucceeded = "success"
+	LoginFailed    = "failed"
+	LoginThrottled = "throttled"
+	// Пароль верный, ждём второй фактор
+	LoginMFARequired = "mfa_required"
+)
+
+type LoginAttempt struct {
+	Email     string  `db:"email"`
+	UserID    *string `db:"user_id"`
+	IP        string  `db:"ip"`
+	UserAgent string  `db:"user_agent"`
+	Result    string  `db:"result"`
+}
+
+type ThrottledResponse struct {
+	Error      string `json:"error"`
+	RetryAfter int64  `json:"retry_after"` // секунды до следующей попытки
+}
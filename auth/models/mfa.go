@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// UserMFA — TOTP пользователя; EnabledAt пуст, пока подключение не подтверждено кодом
+type UserMFA struct {
+	UserID       string     `db:"user_id"`
+	Secret       string     `db:"secret"`
+	EnabledAt    *time.Time `db:"enabled_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+}
+
+// MFAChallenge — вход, прошедший проверку пароля и ожидающий второй фактор
+type MFAChallenge struct {
+	TokenHash string    `db:"token_hash"`
+	UserID    string    `db:"user_id"`
+	Email     string    `db:"email"`
+	Attempts  int       `db:"attempts"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"` // код из приложения или код восстановления
+}
+
+// MFAEnrollRequest — подключение второго фактора требует повторного ввода пароля
+type MFAEnrollRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// MFADisableRequest — для отключения нужны и пароль, и второй фактор
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // код из приложения или код восстановления
+}
+
+// MFARecoveryCodesRequest — новые коды восстановления выдаются по паролю и второму фактору
+type MFARecoveryCodesRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // код из приложения или код восстановления
+}
+
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// MFAChallengeResponse — ответ входа, если у пользователя включена двухфакторная аутентификация
+type MFAChallengeResponse struct {
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"`
+}
+
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// для QR-кода
+}
+
+type MFAStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
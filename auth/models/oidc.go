@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type OIDCState struct {
+	StateHash    string    `db:"state_hash"`
+	Provider     string    `db:"provider"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+type OIDCIdentity struct {
+	Provider string `db:"provider"`
+	Subject  string `db:"subject"`
+	UserID   string `db:"user_id"`
+	Email    string `db:"email"`
+}
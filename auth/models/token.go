@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID           string     `db:"id"`
+	UserID       string     `db:"user_id"`
+	FamilyID     string     `db:"family_id"`
+	TokenHash    string     `db:"token_hash"`
+	TokenVersion int        `db:"token_version"`
+	MFA          bool       `db:"mfa"` // вход подтверждён вторым фактором
+	CreatedAt    time.Time  `db:"created_at"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	RevokedAt    *time.Time `db:"revoked_at"`
+	ReplacedBy   *string    `db:"replaced_by"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
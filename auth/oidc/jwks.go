@@ -0,0 +1,128 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	jwksTTL = time.Hour
+	// Неизвестный kid вызывает перезагрузку не чаще этого
+	jwksMinRefresh = time.Minute
+)
+
+type keySet map[string]jwk
+
+type jwk struct {
+	alg string
+	pub crypto.PublicKey
+}
+
+func (p *Provider) keyfunc(ctx context.Context, meta *metadata) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, err := p.lookupKey(ctx, meta, kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != k.alg {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return k.pub, nil
+	}
+}
+
+func (p *Provider) lookupKey(ctx context.Context, meta *metadata, kid string) (jwk, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var k jwk
+	ok := false
+	if p.keys != nil {
+		k, ok = (*p.keys)[kid]
+	}
+	age := time.Since(p.keysSeen)
+	if age > jwksTTL || !ok && age > jwksMinRefresh {
+		keys, err := p.fetchKeys(ctx, meta.JWKSURI)
+		if err != nil {
+			if ok {
+				return k, nil
+			}
+			return jwk{}, err
+		}
+		p.keys, p.keysSeen = &keys, time.Now()
+		k, ok = keys[kid]
+	}
+	if !ok {
+		return jwk{}, fmt.Errorf("unknown key %q", kid)
+	}
+	return k, nil
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, uri string) (keySet, error) {
+	var set struct {
+		Keys []struct {
+			KeyID string `json:"kid"`
+			Type  string `json:"kty"`
+			Alg   string `json:"alg"`
+			Use   string `json:"use"`
+			Curve string `json:"crv"`
+			X     string `json:"x"`
+			Y     string `json:"y"`
+			N     string `json:"n"`
+			E     string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, uri, &set); err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+
+	b64 := base64.RawURLEncoding
+	keys := make(keySet, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Провайдеры не всегда указывают alg: берём стандартный для типа ключа
+		switch {
+		case k.Type == "RSA" && (k.Alg == "" || k.Alg == "RS256"):
+			n, errN := b64.DecodeString(k.N)
+			e, errE := b64.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.KeyID] = jwk{alg: "RS256", pub: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}}
+		case k.Type == "EC" && k.Curve == "P-256" && (k.Alg == "" || k.Alg == "ES256"):
+			x, errX := b64.DecodeString(k.X)
+			y, errY := b64.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.KeyID] = jwk{alg: "ES256", pub: &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}}
+		case k.Type == "OKP" && k.Curve == "Ed25519":
+			x, err := b64.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				continue
+			}
+			keys[k.KeyID] = jwk{alg: "EdDSA", pub: ed25519.PublicKey(x)}
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,136 @@
+// Пакет passwords — единственное место, где пароли проверяются на соответствие
+// политике, хэшируются и сверяются. Хэши хранит только сервис auth.
+//
+// Поддерживаются argon2id (формат PHC) и bcrypt. Хэш, созданный другим алгоритмом
+// или с параметрами слабее текущих, помечается для пересчёта при следующем входе.
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+
+	// bcrypt учитывает только первые 72 байта
+	maxBytes = 72
+)
+
+type Policy struct {
+	MinLength int
+}
+
+// Validate возвращает ошибку с сообщением для пользователя, если пароль не подходит
+func (p Policy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("Password must be at least %d characters long", p.MinLength)
+	}
+	if len(password) > maxBytes {
+		return fmt.Errorf("Password must be at most %d bytes long", maxBytes)
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		hasLetter = hasLetter || unicode.IsLetter(r)
+		hasDigit = hasDigit || unicode.IsDigit(r)
+	}
+	if !hasLetter || !hasDigit {
+		return errors.New("Password must contain at least one letter and one number")
+	}
+	return nil
+}
+
+// Argon2Params — параметры argon2id; по умолчанию второй вариант из RFC 9106
+type Argon2Params struct {
+	Memory  uint32 // КиБ
+	Time    uint32
+	Threads uint8
+}
+
+var DefaultArgon2 = Argon2Params{Memory: 64 * 1024, Time: 3, Threads: 4}
+
+type Hasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+func (h Hasher) Hash(password string) (string, error) {
+	if h.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("bcrypt: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+	p := h.Argon2
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify сверяет пароль с хэшем. rehash — пароль верный, но хэш нужно пересчитать
+// текущим алгоритмом и параметрами.
+func (h Hasher) Verify(hash, password string) (ok, rehash bool, err error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		var p Argon2Params
+		var version int
+		var salt, key string
+		parts := strings.Split(hash, "$")
+		if len(parts) != 6 {
+			return false, false, ErrUnknownHash
+		}
+		if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+			return false, false, ErrUnknownHash
+		}
+		if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+			return false, false, ErrUnknownHash
+		}
+		salt, key = parts[4], parts[5]
+
+		saltBytes, err1 := base64.RawStdEncoding.DecodeString(salt)
+		want, err2 := base64.RawStdEncoding.DecodeString(key)
+		if err1 != nil || err2 != nil {
+			return false, false, ErrUnknownHash
+		}
+		got := argon2.IDKey([]byte(password), saltBytes, p.Time, p.Memory, p.Threads, uint32(len(want)))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			return false, false, nil
+		}
+		weaker := p.Memory < h.Argon2.Memory || p.Time < h.Argon2.Time || p.Threads < h.Argon2.Threads
+		return true, h.Algorithm != Argon2id || weaker, nil
+
+	case strings.HasPrefix(hash, "$2"):
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, fmt.Errorf("bcrypt: %w", err)
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return false, false, fmt.Errorf("bcrypt: %w", err)
+		}
+		return true, h.Algorithm != Bcrypt || cost < h.BcryptCost, nil
+	}
+	return false, false, ErrUnknownHash
+}
@@ -0,0 +1,116 @@
+package passwords
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Дешёвые параметры, чтобы тесты не тратили время на хэширование
+var (
+	weakArgon2   = Argon2Params{Memory: 1024, Time: 1, Threads: 1}
+	strongArgon2 = Argon2Params{Memory: 2048, Time: 2, Threads: 1}
+)
+
+func mustHash(t *testing.T, h Hasher, password string) string {
+	t.Helper()
+	hash, err := h.Hash(password)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	return hash
+}
+
+func TestPolicyValidate(t *testing.T) {
+	p := Policy{MinLength: 8}
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"valid", "secret123", false},
+		{"too short", "abc1", true},
+		{"multibyte counted as runes", "пароль12", false},
+		{"no digit", "password", true},
+		{"no letter", "12345678", true},
+		{"longer than bcrypt accepts", "a1" + strings.Repeat("x", maxBytes), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.Validate(tt.password); (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) error = %v, wantErr %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const password = "secret123"
+
+	argon := Hasher{Algorithm: Argon2id, BcryptCost: bcrypt.MinCost, Argon2: weakArgon2}
+	argonStrong := Hasher{Algorithm: Argon2id, BcryptCost: bcrypt.MinCost, Argon2: strongArgon2}
+	bcryptMin := Hasher{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost, Argon2: weakArgon2}
+	bcryptHigher := Hasher{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost + 1, Argon2: weakArgon2}
+
+	argonHash := mustHash(t, argon, password)
+	// Хэши bcrypt достались от сервиса users до переноса паролей в auth
+	legacyHash := mustHash(t, bcryptMin, password)
+
+	tests := []struct {
+		name       string
+		hasher     Hasher
+		hash       string
+		password   string
+		wantOK     bool
+		wantRehash bool
+		wantErr    error
+	}{
+		{"argon2id current", argon, argonHash, password, true, false, nil},
+		{"argon2id wrong password", argon, argonHash, "secret124", false, false, nil},
+		{"argon2id weaker params", argonStrong, argonHash, password, true, true, nil},
+		{"argon2id when bcrypt configured", bcryptMin, argonHash, password, true, true, nil},
+		{"legacy bcrypt migrated to argon2id", argon, legacyHash, password, true, true, nil},
+		{"legacy bcrypt wrong password", argon, legacyHash, "secret124", false, false, nil},
+		{"bcrypt current", bcryptMin, legacyHash, password, true, false, nil},
+		{"bcrypt lower cost", bcryptHigher, legacyHash, password, true, true, nil},
+		{"unknown format", argon, "plain:" + password, password, false, false, ErrUnknownHash},
+		{"argon2id truncated", argon, strings.Join(strings.Split(argonHash, "$")[:4], "$"), password, false, false, ErrUnknownHash},
+		{"argon2id wrong version", argon, strings.Replace(argonHash, "v=19", "v=16", 1), password, false, false, ErrUnknownHash},
+		{"argon2id bad salt", argon, "$argon2id$v=19$m=1024,t=1,p=1$!!!$AAAA", password, false, false, ErrUnknownHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := tt.hasher.Verify(tt.hash, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Errorf("Verify() = (%v, %v), want (%v, %v)", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestHashFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+		prefix string
+	}{
+		{"argon2id", Hasher{Algorithm: Argon2id, Argon2: weakArgon2}, "$argon2id$v=19$m=1024,t=1,p=1$"},
+		{"bcrypt", Hasher{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}, "$2a$04$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustHash(t, tt.hasher, "secret123"), mustHash(t, tt.hasher, "secret123")
+			if !strings.HasPrefix(a, tt.prefix) {
+				t.Errorf("Hash() = %s, want prefix %s", a, tt.prefix)
+			}
+			if a == b {
+				t.Errorf("two hashes of the same password are equal: salt is not random")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package throttle
+
+import (
+	"auth/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит счётчики в памяти процесса: для тестов и локального запуска
+// одним экземпляром. При нескольких экземплярах auth нужен общий Store.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]models.ThrottleEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]models.ThrottleEntry)}
+}
+
+func (s *MemoryStore) GetThrottle(ctx context.Context, key string) (*models.ThrottleEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (s *MemoryStore) AddThrottleFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.LastFailureAt.Before(windowStart) {
+		e = models.ThrottleEntry{Key: key, BlockedUntil: e.BlockedUntil}
+	}
+	e.Failures++
+	e.LastFailureAt = now
+	s.entries[key] = e
+	return e.Failures, nil
+}
+
+func (s *MemoryStore) BlockThrottle(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.BlockedUntil = &until
+		s.entries[key] = e
+	}
+	return nil
+}
+
+func (s *MemoryStore) ResetThrottle(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStore) PurgeThrottle(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, e := range s.entries {
+		if e.LastFailureAt.Before(before) && (e.BlockedUntil == nil || e.BlockedUntil.Before(before)) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
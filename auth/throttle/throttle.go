@@ -0,0 +1,103 @@
+// Пакет throttle защищает вход от подбора пароля. Неудачные попытки считаются
+// по ключу (аккаунт или IP); после нескольких бесплатных попыток ключ блокируется
+// на экспоненциально растущее время, а после порога — на время полной блокировки.
+// Счётчики хранятся в Store: в Postgres в рабочем окружении или в памяти для тестов.
+package throttle
+
+import (
+	"auth/models"
+	"context"
+	"time"
+)
+
+type Store interface {
+	// GetThrottle возвращает счётчик ключа; nil — неудач не было
+	GetThrottle(ctx context.Context, key string) (*models.ThrottleEntry, error)
+	// AddThrottleFailure увеличивает счётчик и возвращает новое значение.
+	// Если прошлая неудача была раньше windowStart, счёт начинается заново.
+	AddThrottleFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error)
+	BlockThrottle(ctx context.Context, key string, until time.Time) error
+	ResetThrottle(ctx context.Context, key string) error
+	// PurgeThrottle удаляет счётчики без неудач и блокировок после before
+	PurgeThrottle(ctx context.Context, before time.Time) error
+}
+
+type Policy struct {
+	FreeAttempts int           // неудачи без задержки
+	BaseDelay    time.Duration // задержка после первой платной неудачи, дальше удваивается
+	MaxDelay     time.Duration
+	LockoutAfter int // после стольких неудач ключ блокируется на LockoutFor
+	LockoutFor   time.Duration
+	Window       time.Duration // неудачи старше окна забываются
+}
+
+// delay — на сколько блокируется ключ после failures неудач подряд
+func (p Policy) delay(failures int) time.Duration {
+	if failures >= p.LockoutAfter {
+		return p.LockoutFor
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+	shift := failures - p.FreeAttempts - 1
+	if shift > 30 {
+		return p.MaxDelay
+	}
+	d := p.BaseDelay << shift
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+}
+
+func NewLimiter(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy}
+}
+
+// Blocked возвращает, сколько ещё ждать до следующей попытки; 0 — можно пробовать
+func (l *Limiter) Blocked(ctx context.Context, key string) (time.Duration, error) {
+	e, err := l.store.GetThrottle(ctx, key)
+	if err != nil || e == nil || e.BlockedUntil == nil {
+		return 0, err
+	}
+	if wait := time.Until(*e.BlockedUntil); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Fail учитывает неудачную попытку и возвращает назначенную блокировку
+func (l *Limiter) Fail(ctx context.Context, key string) (time.Duration, error) {
+	now := time.Now().UTC()
+	failures, err := l.store.AddThrottleFailure(ctx, key, now, now.Add(-l.policy.Window))
+	if err != nil {
+		return 0, err
+	}
+
+	d := l.policy.delay(failures)
+	if d > 0 {
+		if err := l.store.BlockThrottle(ctx, key, now.Add(d)); err != nil {
+			return 0, err
+		}
+	}
+	return d, nil
+}
+
+// Reset забывает неудачи ключа после успешного входа
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.ResetThrottle(ctx, key)
+}
+
+// Purge удаляет счётчики, которые уже ни на что не влияют
+func (l *Limiter) Purge(ctx context.Context) error {
+	keep := l.policy.Window
+	if l.policy.LockoutFor > keep {
+		keep = l.policy.LockoutFor
+	}
+	return l.store.PurgeThrottle(ctx, time.Now().UTC().Add(-keep))
+}
@@ -0,0 +1,144 @@
+package throttle
+
+import (
+	"auth/models"
+	"context"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts: 3,
+	BaseDelay:    time.Second,
+	MaxDelay:     10 * time.Second,
+	LockoutAfter: 10,
+	LockoutFor:   time.Hour,
+	Window:       15 * time.Minute,
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{9, 10 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := testPolicy.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	// Большой сдвиг не должен переполнить Duration
+	p := testPolicy
+	p.LockoutAfter = 1000
+	if got := p.delay(200); got != p.MaxDelay {
+		t.Errorf("delay(200) = %v, want %v", got, p.MaxDelay)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		failures  int
+		reset     bool
+		wantDelay time.Duration
+		blocked   bool
+	}{
+		{"free attempts", 3, false, 0, false},
+		{"first paid failure", 4, false, time.Second, true},
+		{"backoff doubles", 6, false, 4 * time.Second, true},
+		{"lockout", 10, false, time.Hour, true},
+		{"reset after success", 10, true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(NewMemoryStore(), testPolicy)
+			var d time.Duration
+			for i := 0; i < tt.failures; i++ {
+				var err error
+				if d, err = l.Fail(ctx, "account:a@example.com"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.reset {
+				if err := l.Reset(ctx, "account:a@example.com"); err != nil {
+					t.Fatal(err)
+				}
+				d = 0
+			}
+			if d != tt.wantDelay {
+				t.Errorf("last Fail() = %v, want %v", d, tt.wantDelay)
+			}
+
+			wait, err := l.Blocked(ctx, "account:a@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (wait > 0) != tt.blocked || wait > tt.wantDelay {
+				t.Errorf("Blocked() = %v, want blocked=%v for at most %v", wait, tt.blocked, tt.wantDelay)
+			}
+			if wait, _ := l.Blocked(ctx, "ip:10.0.0.1"); wait != 0 {
+				t.Errorf("other key blocked for %v", wait)
+			}
+		})
+	}
+}
+
+func TestLimiterWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	l := NewLimiter(store, testPolicy)
+
+	for i := 0; i < 5; i++ {
+		if _, err := l.Fail(ctx, "k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Последняя неудача вышла за окно: счёт начинается заново
+	e := store.entries["k"]
+	e.LastFailureAt = e.LastFailureAt.Add(-testPolicy.Window - time.Minute)
+	store.entries["k"] = e
+
+	d, err := l.Fail(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != 0 {
+		t.Errorf("Fail() after window = %v, want 0", d)
+	}
+	if got := store.entries["k"].Failures; got != 1 {
+		t.Errorf("failures after window = %d, want 1", got)
+	}
+}
+
+func TestLimiterPurge(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	l := NewLimiter(store, testPolicy)
+	old := time.Now().UTC().Add(-2 * testPolicy.LockoutFor)
+	blocked := time.Now().UTC().Add(time.Minute)
+
+	store.entries["stale"] = models.ThrottleEntry{Key: "stale", Failures: 1, LastFailureAt: old}
+	store.entries["fresh"] = models.ThrottleEntry{Key: "fresh", Failures: 1, LastFailureAt: time.Now().UTC()}
+	store.entries["locked"] = models.ThrottleEntry{Key: "locked", Failures: 10, LastFailureAt: old, BlockedUntil: &blocked}
+
+	if err := l.Purge(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"stale": false, "fresh": true, "locked": true} {
+		if _, ok := store.entries[key]; ok != want {
+			t.Errorf("after Purge %q present = %v, want %v", key, ok, want)
+		}
+	}
+}
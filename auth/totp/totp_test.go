@@ -0,0 +1,98 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Секрет из приложения B RFC 6238 для HMAC-SHA1
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// Векторы RFC 6238 (восьмизначные коды): при шести цифрах остаются младшие разряды
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestGenerateRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, v := range rfcVectors {
+		step := v.unix / int64(Period.Seconds())
+		want := v.code[len(v.code)-Digits:]
+		if got := generate(key, step); got != want {
+			t.Errorf("generate(T=%d) = %s, want %s", v.unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / int64(Period.Seconds())
+	code := "050471"
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      time.Time
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, code, now, 0, step, true},
+		{"lowercase secret", strings.ToLower(rfcSecret), code, now, 0, step, true},
+		{"previous step clock skew", rfcSecret, code, now.Add(Period), 0, step, true},
+		{"next step clock skew", rfcSecret, code, now.Add(-Period), 0, step, true},
+		{"too old", rfcSecret, code, now.Add(2 * Period), 0, 0, false},
+		{"already used", rfcSecret, code, now, step, 0, false},
+		{"older step used", rfcSecret, code, now, step - 1, step, true},
+		{"wrong code", rfcSecret, "050472", now, 0, 0, false},
+		{"short code", rfcSecret, "05047", now, 0, 0, false},
+		{"eight digits", rfcSecret, "14050471", now, 0, 0, false},
+		{"bad secret", "not base32!", code, now, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := Validate(tt.secret, tt.code, tt.now, tt.lastStep)
+			if gotOK != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Validate() = (%d, %v), want (%d, %v)", gotStep, gotOK, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecretRoundTrip(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode %q: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Fatalf("secret length = %d bytes, want 20", len(key))
+	}
+
+	now := time.Now()
+	code := generate(key, now.Unix()/int64(Period.Seconds()))
+	if _, ok := Validate(secret, code, now, 0); !ok {
+		t.Errorf("Validate rejected a freshly generated code")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	got := ProvisioningURI("Sudoku", "a b@example.com", "ABC")
+	want := "otpauth://totp/Sudoku:a%20b@example.com?algorithm=SHA1&digits=6&issuer=Sudoku&period=30&secret=ABC"
+	if got != want {
+		t.Errorf("ProvisioningURI() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+// Команда sudokuio импортирует и выгружает головоломки sudoku_fields.
+//
+//	go run ./cmd/sudokuio -import puzzles.sdk [-format sdk] [-difficulty hard]
+//	go run ./cmd/sudokuio -export hard [-tags x-wing,symmetric] [-format csv] [-out hard.csv]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"game/config"
+	"game/database"
+	"game/puzzleio"
+)
+
+func main() {
+	importPath := flag.String("import", "", "file to import puzzles from")
+	exportDifficulty := flag.String("export", "", "difficulty to export")
+	format := flag.String("format", "", "line, sdk or csv (detected from file extension on import)")
+	difficulty := flag.String("difficulty", "", "difficulty for imported puzzles without one (graded automatically if empty)")
+	out := flag.String("out", "", "export destination (stdout if empty)")
+	tags := flag.String("tags", "", "comma-separated tags an exported puzzle must have")
+	flag.Parse()
+
+	if (*importPath == "") == (*exportDifficulty == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		log.Fatalf("failed to init database: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if *importPath != "" {
+		f, err := os.Open(*importPath)
+		if err != nil {
+			log.Fatalf("open %s: %v", *importPath, err)
+		}
+		defer f.Close()
+
+		if *format == "" {
+			*format = puzzleio.FormatFromFilename(*importPath)
+		}
+
+		records, err := puzzleio.Read(f, *format)
+		if err != nil {
+			log.Fatalf("read %s: %v", *importPath, err)
+		}
+
+		report, err := puzzleio.Import(ctx, db, records, *difficulty)
+		if report != nil {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(report)
+		}
+		if err != nil {
+			log.Fatalf("import failed: %v", err)
+		}
+		return
+	}
+
+	fields, err := db.GetFieldsByComplexity(ctx, *exportDifficulty, splitTags(*tags))
+	if err != nil {
+		log.Fatalf("get sudokus: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "" {
+		*format = puzzleio.FormatFromFilename(*out)
+	}
+	if err := puzzleio.Write(w, *format, puzzleio.FromFields(fields)); err != nil {
+		log.Fatalf("export failed: %v", err)
+	}
+	log.Printf("exported %d %s puzzles", len(fields), *exportDifficulty)
+}
+
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
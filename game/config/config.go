@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +18,20 @@ type Config struct {
 
 	ServerPort string
 	UsersURL   string
+
+	TournamentURL string
+
+	// Общий секрет для подписи и проверки запросов между сервисами
+	ServiceTokenSecret string
+
+	PoolMinSize       int
+	PoolCheckInterval time.Duration
+
+	OutboxInterval    time.Duration
+	OutboxMaxAttempts int
+
+	AchievementStatsInterval time.Duration
+	ActivePlayerWindow       time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -30,6 +46,19 @@ func LoadConfig() (*Config, error) {
 
 		ServerPort: getEnv("SERVER_PORT"),
 		UsersURL:   getEnv("USERS_SERVICE_URL"),
+
+		TournamentURL: getEnv("TOURNAMENT_SERVICE_URL"),
+
+		ServiceTokenSecret: getEnv("SERVICE_TOKEN_SECRET"),
+
+		PoolMinSize:       getEnvInt("SUDOKU_POOL_MIN_SIZE", 50),
+		PoolCheckInterval: getEnvDuration("SUDOKU_POOL_CHECK_INTERVAL", 10*time.Minute),
+
+		OutboxInterval:    getEnvDuration("OUTBOX_DISPATCH_INTERVAL", 5*time.Second),
+		OutboxMaxAttempts: getEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+
+		AchievementStatsInterval: getEnvDuration("ACHIEVEMENT_STATS_INTERVAL", 15*time.Minute),
+		ActivePlayerWindow:       getEnvDuration("ACTIVE_PLAYER_WINDOW", 30*24*time.Hour),
 	}
 
 	return cfg, nil
@@ -42,3 +71,27 @@ func getEnv(key string) string {
 	}
 	return val
 }
+
+func getEnvInt(key string, fallback int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		panic(fmt.Sprintf("invalid integer in environment variable %s: %v", key, err))
+	}
+	return n
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		panic(fmt.Sprintf("invalid duration in environment variable %s: %v", key, err))
+	}
+	return d
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"game/models"
+	"time"
+)
+
+// RefreshAchievementStats пересчитывает агрегат achievement_stats.
+// Активные игроки — завершившие игру за последние window.
+func (d *Database) RefreshAchievementStats(ctx context.Context, window time.Duration, recentLimit int) error {
+	const query = `
+		WITH active AS (
+			SELECT DISTINCT user_id FROM game_sessions
+			WHERE status = 'completed' AND completed_at >= NOW() - $1 * INTERVAL '1 second'
+		), total AS (
+			SELECT COUNT(*) AS n FROM active
+		), counts AS (
+			SELECT a.id AS achievement_id,
+			       COUNT(ua.user_id) AS earned_count,
+			       COUNT(ua.user_id) FILTER (WHERE ua.user_id IN (SELECT user_id FROM active)) AS active_earned_count
+			FROM achievements a
+			LEFT JOIN user_achievements ua ON ua.achievement_id = a.id
+			GROUP BY a.id
+		)
+		INSERT INTO achievement_stats (
+			achievement_id, earned_count, active_earned_count, active_players,
+			earned_percent, first_earner, recent_earners, refreshed_at
+		)
+		SELECT c.achievement_id, c.earned_count, c.active_earned_count, t.n,
+		       CASE WHEN t.n = 0 THEN 0 ELSE 100.0 * c.active_earned_count / t.n END,
+		       (
+		           SELECT jsonb_build_object('user_id', ua.user_id, 'username', COALESCE(u.username, ''), 'earned_at', ua.earned_at)
+		           FROM user_achievements ua
+		           LEFT JOIN users u ON u.id = ua.user_id
+		           WHERE ua.achievement_id = c.achievement_id
+		           ORDER BY ua.earned_at, ua.user_id
+		           LIMIT 1
+		       ),
+		       COALESCE((
+		           SELECT jsonb_agg(jsonb_build_object('user_id', r.user_id, 'username', r.username, 'earned_at', r.earned_at)
+		                            ORDER BY r.earned_at DESC)
+		           FROM (
+		               SELECT ua.user_id, COALESCE(u.username, '') AS username, ua.earned_at
+		               FROM user_achievements ua
+		               LEFT JOIN users u ON u.id = ua.user_id
+		               WHERE ua.achievement_id = c.achievement_id
+		               ORDER BY ua.earned_at DESC
+		               LIMIT $2
+		           ) r
+		       ), '[]'::jsonb),
+		       NOW()
+		FROM counts c, total t
+		ON CONFLICT (achievement_id) DO UPDATE
+		SET earned_count = EXCLUDED.earned_count,
+		    active_earned_count = EXCLUDED.active_earned_count,
+		    active_players = EXCLUDED.active_players,
+		    earned_percent = EXCLUDED.earned_percent,
+		    first_earner = EXCLUDED.first_earner,
+		    recent_earners = EXCLUDED.recent_earners,
+		    refreshed_at = EXCLUDED.refreshed_at
+	`
+
+	if _, err := d.DB.ExecContext(ctx, query, int64(window.Seconds()), recentLimit); err != nil {
+		return fmt.Errorf("refresh achievement stats: %w", err)
+	}
+	return nil
+}
+
+const achievementStatsQuery = `
+	SELECT a.code, a.title, s.earned_count, s.active_earned_count, s.active_players,
+	       s.earned_percent, s.active_players > 0 AND s.earned_percent < $1 AS rare,
+	       s.refreshed_at, s.first_earner, s.recent_earners
+	FROM achievement_stats s
+	JOIN achievements a ON a.id = s.achievement_id
+`
+
+type achievementStatsRow struct {
+	models.AchievementStats
+	RawFirstEarner   []byte `db:"first_earner"`
+	RawRecentEarners []byte `db:"recent_earners"`
+}
+
+func (r *achievementStatsRow) toModel() (models.AchievementStats, error) {
+	s := r.AchievementStats
+	if len(r.RawFirstEarner) > 0 {
+		if err := json.Unmarshal(r.RawFirstEarner, &s.FirstEarner); err != nil {
+			return s, fmt.Errorf("unmarshal first earner: %w", err)
+		}
+	}
+	s.RecentEarners = []models.AchievementEarner{}
+	if err := json.Unmarshal(r.RawRecentEarners, &s.RecentEarners); err != nil {
+		return s, fmt.Errorf("unmarshal recent earners: %w", err)
+	}
+	return s, nil
+}
+
+func (d *Database) GetAchievementStats(ctx context.Context) ([]models.AchievementStats, error) {
+	var rows []achievementStatsRow
+	if err := d.DB.SelectContext(ctx, &rows, achievementStatsQuery+` ORDER BY s.earned_percent, a.code`, models.RareAchievementPercent); err != nil {
+		return nil, fmt.Errorf("get achievement stats: %w", err)
+	}
+
+	stats := make([]models.AchievementStats, 0, len(rows))
+	for _, r := range rows {
+		s, err := r.toModel()
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+func (d *Database) GetAchievementStatsByCode(ctx context.Context, code string) (*models.AchievementStats, error) {
+	var row achievementStatsRow
+	err := d.DB.GetContext(ctx, &row, achievementStatsQuery+` WHERE a.code = $2`, models.RareAchievementPercent, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get achievement stats: %w", err)
+	}
+	s, err := row.toModel()
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetUserAchievementsWithRarity возвращает полученные достижения с долей игроков, у которых они есть
+func (d *Database) GetUserAchievementsWithRarity(ctx context.Context, userID string) ([]models.AchievementResponse, error) {
+	achievements := []models.AchievementResponse{}
+	err := d.DB.SelectContext(ctx, &achievements, `
+		SELECT a.code, a.title, COALESCE(a.description, '') AS description, COALESCE(a.icon_url, '') AS icon_url,
+		       COALESCE(a.series, '') AS series, COALESCE(a.tier, '') AS tier, a.points,
+		       COALESCE(s.earned_percent, 0) AS earned_percent,
+		       COALESCE(s.active_players > 0 AND s.earned_percent < $2, FALSE) AS rare
+		FROM user_achievements ua
+		JOIN achievements a ON a.id = ua.achievement_id
+		LEFT JOIN achievement_stats s ON s.achievement_id = a.id
+		WHERE ua.user_id = $1
+		ORDER BY ua.earned_at DESC
+	`, userID, models.RareAchievementPercent)
+	if err != nil {
+		return nil, fmt.Errorf("get user achievements: %w", err)
+	}
+	return achievements, nil
+}
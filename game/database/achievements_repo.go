@@ -4,17 +4,58 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"game/models"
 
 	_ "github.com/lib/pq"
 )
 
+const achievementColumns = `
+	id, code, title, description, icon_url, condition,
+	COALESCE(series, ''), COALESCE(tier, ''), points, created_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAchievement(row rowScanner) (models.Achievement, error) {
+	var (
+		a            models.Achievement
+		rawCondition []byte
+	)
+	if err := row.Scan(
+		&a.ID,
+		&a.Code,
+		&a.Title,
+		&a.Description,
+		&a.IconURL,
+		&rawCondition,
+		&a.Series,
+		&a.Tier,
+		&a.Points,
+		&a.CreatedAt,
+	); err != nil {
+		return models.Achievement{}, err
+	}
+	if err := json.Unmarshal(rawCondition, &a.Condition); err != nil {
+		return models.Achievement{}, fmt.Errorf("unmarshal condition: %w", err)
+	}
+	return a, nil
+}
+
 func (d *Database) GetAllAchievements(ctx context.Context) ([]models.Achievement, error) {
-	rows, err := d.DB.QueryContext(ctx, `
-		SELECT id, code, title, description, icon_url, condition, created_at
-		FROM achievements
-	`)
+	return d.queryAchievements(ctx, `SELECT `+achievementColumns+` FROM achievements`)
+}
+
+// GetAchievementsBySeries возвращает уровни серии
+func (d *Database) GetAchievementsBySeries(ctx context.Context, series string) ([]models.Achievement, error) {
+	return d.queryAchievements(ctx, `SELECT `+achievementColumns+` FROM achievements WHERE series = $1`, series)
+}
+
+func (d *Database) queryAchievements(ctx context.Context, query string, args ...any) ([]models.Achievement, error) {
+	rows, err := d.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("get achievements: %w", err)
 	}
@@ -22,53 +63,24 @@ func (d *Database) GetAllAchievements(ctx context.Context) ([]models.Achievement
 
 	var achievements []models.Achievement
 	for rows.Next() {
-		var rawCondition []byte
-		var a models.Achievement
-		if err := rows.Scan(
-			&a.ID,
-			&a.Code,
-			&a.Title,
-			&a.Description,
-			&a.IconURL,
-			&rawCondition,
-			&a.CreatedAt,
-		); err != nil {
+		a, err := scanAchievement(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan achievement: %w", err)
 		}
-		if err := json.Unmarshal(rawCondition, &a.Condition); err != nil {
-			return nil, fmt.Errorf("unmarshal condition: %w", err)
-		}
 		achievements = append(achievements, a)
 	}
-	return achievements, nil
+	return achievements, rows.Err()
 }
 
 func (d *Database) GetAchievementByCode(code string) (models.Achievement, error) {
-	var (
-		a            models.Achievement
-		rawCondition []byte
-	)
-	err := d.DB.QueryRowContext(context.Background(), `
-		SELECT id, code, title, description, icon_url, condition, created_at
+	a, err := scanAchievement(d.DB.QueryRowContext(context.Background(), `
+		SELECT `+achievementColumns+`
 		FROM achievements
 		WHERE code = $1
-	`, code).Scan(
-		&a.ID,
-		&a.Code,
-		&a.Title,
-		&a.Description,
-		&a.IconURL,
-		&rawCondition,
-		&a.CreatedAt,
-	)
+	`, code))
 	if err != nil {
 		return models.Achievement{}, fmt.Errorf("get achievement: %w", err)
 	}
-
-	if err := json.Unmarshal(rawCondition, &a.Condition); err != nil {
-		return models.Achievement{}, fmt.Errorf("unmarshal condition: %w", err)
-	}
-
 	return a, nil
 }
 
@@ -79,9 +91,9 @@ func (d *Database) InsertAchievement(ctx context.Context, a models.Achievement)
 	}
 
 	_, err = d.DB.ExecContext(ctx, `
-		INSERT INTO achievements (code, title, description, icon_url, condition)
-		VALUES ($1, $2, $3, $4, $5)
-	`, a.Code, a.Title, a.Description, a.IconURL, conditionJSON)
+		INSERT INTO achievements (code, title, description, icon_url, condition, series, tier, points)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8)
+	`, a.Code, a.Title, a.Description, a.IconURL, conditionJSON, a.Series, a.Tier, a.Points)
 	if err != nil {
 		return fmt.Errorf("insert achievement: %w", err)
 	}
@@ -89,16 +101,21 @@ func (d *Database) InsertAchievement(ctx context.Context, a models.Achievement)
 	return nil
 }
 
-func (d *Database) UpdateAchievement(ctx context.Context, code, title, description, icon, condition string) error {
+// UpdateAchievement меняет только заполненные поля формы
+func (d *Database) UpdateAchievement(ctx context.Context, code, icon string, form models.UpdateAchievementForm) error {
 	query := `
 		UPDATE achievements
 		SET title = COALESCE(NULLIF($2, ''), title),
 		    description = COALESCE(NULLIF($3, ''), description),
 		    icon_url = CASE WHEN $4 != '' THEN $4 ELSE icon_url END,
-		    condition = CASE WHEN $5 != '' THEN $5::jsonb ELSE condition END
+		    condition = CASE WHEN $5 != '' THEN $5::jsonb ELSE condition END,
+		    series = COALESCE(NULLIF($6, ''), series),
+		    tier = COALESCE(NULLIF($7, ''), tier),
+		    points = COALESCE($8, points)
 		WHERE code = $1
 	`
-	_, err := d.DB.ExecContext(ctx, query, code, title, description, icon, condition)
+	_, err := d.DB.ExecContext(ctx, query, code, form.Title, form.Description, icon, form.Condition,
+		form.Series, form.Tier, form.Points)
 	if err != nil {
 		return fmt.Errorf("update achievement: %w", err)
 	}
@@ -127,3 +144,41 @@ func (d *Database) DeleteAchievement(code string) error {
 	}
 	return nil
 }
+
+const achievementScoresQuery = `
+	SELECT RANK() OVER (ORDER BY SUM(a.points) DESC) AS rank,
+	       ua.user_id, COALESCE(u.username, '') AS username,
+	       SUM(a.points) AS score, COUNT(*) AS achievements
+	FROM user_achievements ua
+	JOIN achievements a ON a.id = ua.achievement_id
+	LEFT JOIN users u ON u.id = ua.user_id
+	GROUP BY ua.user_id, u.username
+`
+
+// GetAchievementLeaderboard возвращает рейтинг игроков по очкам достижений
+func (d *Database) GetAchievementLeaderboard(ctx context.Context, limit int) ([]models.AchievementScore, error) {
+	scores := []models.AchievementScore{}
+	err := d.DB.SelectContext(ctx, &scores, achievementScoresQuery+`
+		ORDER BY score DESC, username
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get achievement leaderboard: %w", err)
+	}
+	return scores, nil
+}
+
+// GetAchievementScore возвращает очки и место пользователя; без достижений — нулевой счёт без места
+func (d *Database) GetAchievementScore(ctx context.Context, userID string) (*models.AchievementScore, error) {
+	var score models.AchievementScore
+	err := d.DB.GetContext(ctx, &score, `
+		SELECT * FROM (`+achievementScoresQuery+`) s WHERE s.user_id = $1
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.AchievementScore{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get achievement score: %w", err)
+	}
+	return &score, nil
+}
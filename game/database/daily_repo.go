@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"game/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrDailyAttempted = errors.New("daily puzzle already attempted")
+
+// EnsureDailyPuzzle возвращает головоломку дня, при первом обращении закрепляя её.
+// Выбор детерминирован ключом key: ближайшее по random_key поле, которое ещё
+// не было головоломкой дня; если такие закончились — любое поле сложности.
+func (d *Database) EnsureDailyPuzzle(ctx context.Context, date time.Time, difficulty string, key float64) (*models.SudokuField, error) {
+	field, err := d.getDailyPuzzle(ctx, date, difficulty)
+	if err != nil || field != nil {
+		return field, err
+	}
+
+	const pick = `
+		INSERT INTO daily_puzzles (date, difficulty, field_id)
+		SELECT $1::date, $2::text, f.id
+		FROM sudoku_fields f
+		WHERE f.complexity = $2 AND f.random_key %s $3 %s
+		ORDER BY f.random_key %s
+		LIMIT 1
+		ON CONFLICT (date, difficulty) DO NOTHING
+	`
+	const unused = `AND NOT EXISTS (SELECT 1 FROM daily_puzzles p WHERE p.field_id = f.id)`
+
+	variants := [][3]string{
+		{">=", unused, "ASC"},
+		{"<", unused, "DESC"},
+		{">=", "", "ASC"},
+		{"<", "", "DESC"},
+	}
+	for _, v := range variants {
+		if _, err := d.DB.ExecContext(ctx, fmt.Sprintf(pick, v[0], v[1], v[2]), date, difficulty, key); err != nil {
+			return nil, fmt.Errorf("pick daily puzzle: %w", err)
+		}
+		// Запись могла появиться и от параллельного запроса — читаем закреплённую
+		field, err := d.getDailyPuzzle(ctx, date, difficulty)
+		if err != nil || field != nil {
+			return field, err
+		}
+	}
+	return nil, nil
+}
+
+func (d *Database) getDailyPuzzle(ctx context.Context, date time.Time, difficulty string) (*models.SudokuField, error) {
+	return scanField(d.DB.QueryRowContext(ctx, `
+		SELECT`+fieldColumns+`
+		FROM daily_puzzles p
+		JOIN sudoku_fields f ON f.id = p.field_id
+		WHERE p.date = $1 AND p.difficulty = $2
+	`, date, difficulty))
+}
+
+// IsDailyPuzzle сообщает, закреплено ли поле головоломкой дня на date
+func (d *Database) IsDailyPuzzle(ctx context.Context, date time.Time, fieldID string) (bool, error) {
+	var exists bool
+	err := d.DB.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM daily_puzzles WHERE date = $1 AND field_id = $2)
+	`, date, fieldID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check daily puzzle: %w", err)
+	}
+	return exists, nil
+}
+
+func (d *Database) GetDailyAttempt(ctx context.Context, date time.Time, difficulty, userID string) (*models.DailyAttempt, error) {
+	var a models.DailyAttempt
+	err := d.DB.GetContext(ctx, &a, `
+		SELECT date, difficulty, user_id, session_id, started_at, completed_at, elapsed_ms, ranked
+		FROM daily_attempts
+		WHERE date = $1 AND difficulty = $2 AND user_id = $3
+	`, date, difficulty, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get daily attempt: %w", err)
+	}
+	return &a, nil
+}
+
+// StartDailyAttempt записывает попытку и, если newSession, создаёт её сессию.
+// Существующая сессия, приостановленная до начала попытки, возобновляется:
+// попытку дня нельзя держать на паузе
+func (d *Database) StartDailyAttempt(ctx context.Context, a *models.DailyAttempt, s *models.GameSession, newSession bool) error {
+	return d.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if newSession {
+			if err := insertSession(ctx, tx, s); err != nil {
+				return err
+			}
+		} else if s.Status == models.SessionStatusPaused {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE game_sessions SET status = $2, resumed_at = $3
+				WHERE id = $1 AND status = $4
+			`, s.ID, models.SessionStatusActive, a.StartedAt, models.SessionStatusPaused)
+			if err != nil {
+				return fmt.Errorf("resume session: %w", err)
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO daily_attempts (date, difficulty, user_id, session_id, started_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT DO NOTHING
+		`, a.Date, a.Difficulty, a.UserID, a.SessionID, a.StartedAt)
+		if err != nil {
+			return fmt.Errorf("create daily attempt: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		}
+		if affected == 0 {
+			return ErrDailyAttempted
+		}
+		return nil
+	})
+}
+
+// IsDailyAttemptTx сообщает, идёт ли в сессии незавершённая попытка дня
+func (d *Database) IsDailyAttemptTx(ctx context.Context, tx *sqlx.Tx, sessionID string) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM daily_attempts WHERE session_id = $1 AND completed_at IS NULL)
+	`, sessionID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check daily attempt: %w", err)
+	}
+	return exists, nil
+}
+
+// CompleteDailyAttemptTx засчитывает попытку, если сессия была попыткой дня,
+// и продлевает серию дней. Попытка с ranked = false остаётся вне рейтинга дня.
+func (d *Database) CompleteDailyAttemptTx(ctx context.Context, tx *sqlx.Tx, sessionID string, elapsedMs int64, completedAt time.Time, ranked bool) error {
+	var userID string
+	var date time.Time
+	err := tx.QueryRowContext(ctx, `
+		UPDATE daily_attempts
+		SET completed_at = $2, elapsed_ms = $3, ranked = $4
+		WHERE session_id = $1 AND completed_at IS NULL
+		RETURNING user_id, date
+	`, sessionID, completedAt, elapsedMs, ranked).Scan(&userID, &date)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("complete daily attempt: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO daily_streaks (user_id, current_streak, best_streak, last_date)
+		VALUES ($1, 1, 1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET current_streak = CASE
+		        WHEN daily_streaks.last_date = EXCLUDED.last_date - 1 THEN daily_streaks.current_streak + 1
+		        WHEN daily_streaks.last_date >= EXCLUDED.last_date THEN daily_streaks.current_streak
+		        ELSE 1
+		    END,
+		    last_date = GREATEST(daily_streaks.last_date, EXCLUDED.last_date)
+	`, userID, date)
+	if err != nil {
+		return fmt.Errorf("update daily streak: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE daily_streaks SET best_streak = GREATEST(best_streak, current_streak) WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("update best daily streak: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetDailyStreak(ctx context.Context, userID string) (models.DailyStreak, error) {
+	var s models.DailyStreak
+	err := d.DB.GetContext(ctx, &s, `
+		SELECT current_streak, best_streak, last_date FROM daily_streaks WHERE user_id = $1
+	`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("get daily streak: %w", err)
+	}
+	return s, nil
+}
+
+func (d *Database) GetDailyLeaderboard(ctx context.Context, date time.Time, difficulty string, limit int) ([]models.DailyLeaderboardEntry, error) {
+	entries := []models.DailyLeaderboardEntry{}
+	err := d.DB.SelectContext(ctx, &entries, `
+		SELECT RANK() OVER (ORDER BY a.elapsed_ms) AS rank,
+		       a.user_id, COALESCE(u.username, '') AS username,
+		       a.elapsed_ms, a.completed_at
+		FROM daily_attempts a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.date = $1 AND a.difficulty = $2 AND a.completed_at IS NOT NULL AND a.ranked
+		ORDER BY a.elapsed_ms, a.completed_at
+		LIMIT $3
+	`, date, difficulty, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get daily leaderboard: %w", err)
+	}
+	return entries, nil
+}
+
+// GetDailyRank возвращает место пользователя в рейтинге дня
+func (d *Database) GetDailyRank(ctx context.Context, date time.Time, difficulty string, elapsedMs int64) (int, error) {
+	var faster int
+	err := d.DB.GetContext(ctx, &faster, `
+		SELECT COUNT(*) FROM daily_attempts
+		WHERE date = $1 AND difficulty = $2 AND completed_at IS NOT NULL AND ranked AND elapsed_ms < $3
+	`, date, difficulty, elapsedMs)
+	if err != nil {
+		return 0, fmt.Errorf("get daily rank: %w", err)
+	}
+	return faster + 1, nil
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	_ "github.com/lib/pq"
+)
+
+// AddHint засчитывает подсказку в попытку и возвращает число подсказок в ней
+func (d *Database) AddHint(ctx context.Context, sessionID string, cell int) (int, error) {
+	_, err := d.DB.ExecContext(ctx, `
+		INSERT INTO sudoku_hints (session_id, cell)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, sessionID, cell)
+	if err != nil {
+		return 0, fmt.Errorf("insert hint: %w", err)
+	}
+
+	return d.CountHints(ctx, sessionID)
+}
+
+func (d *Database) CountHints(ctx context.Context, sessionID string) (int, error) {
+	var count int
+	err := d.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM sudoku_hints WHERE session_id = $1
+	`, sessionID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count hints: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// MarkSudokuSeen отмечает, что поле было выдано пользователю
+func (d *Database) MarkSudokuSeen(ctx context.Context, userID, fieldID string) error {
+	_, err := d.DB.ExecContext(ctx, `
+		INSERT INTO user_sudoku_history (user_id, field_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, field_id) DO UPDATE SET last_seen_at = CURRENT_TIMESTAMP
+	`, userID, fieldID)
+	if err != nil {
+		return fmt.Errorf("mark sudoku seen: %w", err)
+	}
+	return nil
+}
+
+func recordSolveTx(ctx context.Context, tx *sqlx.Tx, userID, fieldID string, solveTimeMs int64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO user_sudoku_history (user_id, field_id, solved_at, solve_count, best_time_ms)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, 1, $3)
+		ON CONFLICT (user_id, field_id) DO UPDATE
+		SET solved_at = CURRENT_TIMESTAMP,
+		    last_seen_at = CURRENT_TIMESTAMP,
+		    solve_count = user_sudoku_history.solve_count + 1,
+		    best_time_ms = LEAST(user_sudoku_history.best_time_ms, EXCLUDED.best_time_ms)
+	`, userID, fieldID, solveTimeMs)
+	if err != nil {
+		return fmt.Errorf("record solve: %w", err)
+	}
+	return nil
+}
+
+// GetSolvedFieldIDs возвращает, какие из полей пользователь уже решал
+func (d *Database) GetSolvedFieldIDs(ctx context.Context, userID string, fieldIDs []string) (map[string]bool, error) {
+	solved := make(map[string]bool)
+	if userID == "" || len(fieldIDs) == 0 {
+		return solved, nil
+	}
+
+	var ids []string
+	err := d.DB.SelectContext(ctx, &ids, `
+		SELECT field_id FROM user_sudoku_history
+		WHERE user_id = $1 AND field_id = ANY($2) AND solved_at IS NOT NULL
+	`, userID, pq.Array(fieldIDs))
+	if err != nil {
+		return nil, fmt.Errorf("get solved fields: %w", err)
+	}
+	for _, id := range ids {
+		solved[id] = true
+	}
+	return solved, nil
+}
+
+// GetLongestSolveStreak возвращает самую длинную серию дней подряд (UTC),
+// в каждый из которых пользователь завершил хотя бы одну игру
+func (d *Database) GetLongestSolveStreak(ctx context.Context, userID string) (int, error) {
+	var streak int
+	err := d.DB.GetContext(ctx, &streak, `
+		SELECT COALESCE(MAX(days), 0) FROM (
+			SELECT COUNT(*) AS days
+			FROM (
+				SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS grp
+				FROM (
+					SELECT DISTINCT completed_at::date AS day
+					FROM game_sessions
+					WHERE user_id = $1 AND status = 'completed'
+				) solved_days
+			) grouped
+			GROUP BY grp
+		) streaks
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("get longest solve streak: %w", err)
+	}
+	return streak, nil
+}
+
+// CountNoHintSolves считает завершённые без подсказок игры по сложностям;
+// подсказки учитываются в той попытке, в которой были взяты
+func (d *Database) CountNoHintSolves(ctx context.Context, userID string) (map[string]int, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT f.complexity, COUNT(*)
+		FROM game_sessions s
+		JOIN sudoku_fields f ON f.id = s.field_id
+		WHERE s.user_id = $1 AND s.status = 'completed'
+		  AND NOT EXISTS (
+			SELECT 1 FROM sudoku_hints h WHERE h.session_id = s.id
+		  )
+		GROUP BY f.complexity
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count no-hint solves: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var difficulty string
+		var count int
+		if err := rows.Scan(&difficulty, &count); err != nil {
+			return nil, fmt.Errorf("scan no-hint solves: %w", err)
+		}
+		counts[difficulty] = count
+	}
+	return counts, nil
+}
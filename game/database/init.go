@@ -60,9 +60,12 @@ func (d *Database) createTables(ctx context.Context) error {
 			solve_attempts INTEGER NOT NULL DEFAULT 0,
 			solves_successful INTEGER NOT NULL DEFAULT 0,
 			solves_total_time BIGINT NOT NULL DEFAULT 0,
+			random_key DOUBLE PRECISION NOT NULL DEFAULT random(),
 
 			UNIQUE (initial_field, solution)
 		)`,
+		`ALTER TABLE sudoku_fields ADD COLUMN IF NOT EXISTS random_key DOUBLE PRECISION NOT NULL DEFAULT random()`,
+		`CREATE INDEX IF NOT EXISTS sudoku_fields_random_idx ON sudoku_fields (complexity, random_key)`,
 		`CREATE TABLE IF NOT EXISTS sudoku_tags (
 			id VARCHAR(36) PRIMARY KEY,
 			name TEXT UNIQUE NOT NULL
@@ -72,6 +75,117 @@ func (d *Database) createTables(ctx context.Context) error {
 			tag_id VARCHAR(36) NOT NULL REFERENCES sudoku_tags(id) ON DELETE CASCADE,
 			PRIMARY KEY (field_id, tag_id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS user_sudoku_history (
+			user_id VARCHAR(36) NOT NULL,
+			field_id VARCHAR(36) NOT NULL REFERENCES sudoku_fields(id) ON DELETE CASCADE,
+			first_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			solved_at TIMESTAMP,
+			solve_count INTEGER NOT NULL DEFAULT 0,
+			best_time_ms BIGINT,
+			PRIMARY KEY (user_id, field_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS game_sessions (
+			id VARCHAR(36) PRIMARY KEY,
+			user_id VARCHAR(36) NOT NULL,
+			field_id VARCHAR(36) NOT NULL REFERENCES sudoku_fields(id) ON DELETE CASCADE,
+			status TEXT NOT NULL,
+			current_grid TEXT NOT NULL,
+			pencil_marks JSONB NOT NULL DEFAULT '{}'::jsonb,
+			started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			resumed_at TIMESTAMP,
+			elapsed_ms BIGINT NOT NULL DEFAULT 0,
+			completed_at TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`ALTER TABLE game_sessions ADD COLUMN IF NOT EXISTS checks_used INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE game_sessions ADD COLUMN IF NOT EXISTS ranked BOOLEAN NOT NULL DEFAULT TRUE`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS game_sessions_open_idx
+			ON game_sessions (user_id, field_id)
+			WHERE status IN ('active', 'paused')`,
+		`CREATE TABLE IF NOT EXISTS sudoku_hints (
+			session_id VARCHAR(36) NOT NULL REFERENCES game_sessions(id) ON DELETE CASCADE,
+			cell INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (session_id, cell)
+		)`,
+		// Раньше подсказки хранились на пользователя и поле; переносим их
+		// в сессию, в которой они были взяты
+		`DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.columns
+			           WHERE table_name = 'sudoku_hints' AND column_name = 'user_id') THEN
+				ALTER TABLE sudoku_hints ADD COLUMN session_id VARCHAR(36)
+					REFERENCES game_sessions(id) ON DELETE CASCADE;
+				UPDATE sudoku_hints h SET session_id = (
+					SELECT s.id FROM game_sessions s
+					WHERE s.user_id = h.user_id AND s.field_id = h.field_id
+					  AND s.started_at <= h.created_at
+					ORDER BY s.started_at DESC
+					LIMIT 1
+				);
+				DELETE FROM sudoku_hints WHERE session_id IS NULL;
+				ALTER TABLE sudoku_hints DROP CONSTRAINT sudoku_hints_pkey;
+				ALTER TABLE sudoku_hints DROP COLUMN user_id, DROP COLUMN field_id;
+				ALTER TABLE sudoku_hints ALTER COLUMN session_id SET NOT NULL;
+				ALTER TABLE sudoku_hints ADD PRIMARY KEY (session_id, cell);
+			END IF;
+		END $$`,
+		`CREATE TABLE IF NOT EXISTS game_session_moves (
+			id BIGSERIAL PRIMARY KEY,
+			session_id VARCHAR(36) NOT NULL REFERENCES game_sessions(id) ON DELETE CASCADE,
+			cell INTEGER NOT NULL,
+			value INTEGER NOT NULL,
+			prev_value INTEGER NOT NULL,
+			pencil_marks TEXT NOT NULL DEFAULT '',
+			prev_pencil_marks TEXT NOT NULL DEFAULT '',
+			undone BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS daily_puzzles (
+			date DATE NOT NULL,
+			difficulty TEXT NOT NULL,
+			field_id VARCHAR(36) NOT NULL REFERENCES sudoku_fields(id) ON DELETE CASCADE,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (date, difficulty)
+		)`,
+		`CREATE INDEX IF NOT EXISTS daily_puzzles_field_idx ON daily_puzzles (field_id)`,
+		`CREATE TABLE IF NOT EXISTS daily_attempts (
+			date DATE NOT NULL,
+			difficulty TEXT NOT NULL,
+			user_id VARCHAR(36) NOT NULL,
+			session_id VARCHAR(36) NOT NULL UNIQUE REFERENCES game_sessions(id) ON DELETE CASCADE,
+			started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed_at TIMESTAMP,
+			elapsed_ms BIGINT,
+			PRIMARY KEY (date, difficulty, user_id)
+		)`,
+		`ALTER TABLE daily_attempts ADD COLUMN IF NOT EXISTS ranked BOOLEAN NOT NULL DEFAULT TRUE`,
+		`CREATE INDEX IF NOT EXISTS daily_attempts_rank_idx
+			ON daily_attempts (date, difficulty, elapsed_ms)
+			WHERE completed_at IS NOT NULL`,
+		`CREATE TABLE IF NOT EXISTS daily_streaks (
+			user_id VARCHAR(36) PRIMARY KEY,
+			current_streak INTEGER NOT NULL DEFAULT 0,
+			best_streak INTEGER NOT NULL DEFAULT 0,
+			last_date DATE
+		)`,
+		`CREATE TABLE IF NOT EXISTS outbox_events (
+			id BIGSERIAL PRIMARY KEY,
+			event_type TEXT NOT NULL,
+			idempotency_key TEXT UNIQUE NOT NULL,
+			payload JSONB NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			locked_until TIMESTAMP,
+			last_error TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			delivered_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS outbox_events_pending_idx
+			ON outbox_events (next_attempt_at)
+			WHERE status = 'pending'`,
 		`CREATE TABLE IF NOT EXISTS achievements (
 			id SERIAL PRIMARY KEY,
 			code TEXT UNIQUE NOT NULL,
@@ -81,12 +195,30 @@ func (d *Database) createTables(ctx context.Context) error {
 			condition JSONB NOT NULL DEFAULT '{}'::jsonb,
 			created_at TIMESTAMP DEFAULT NOW()
 		)`,
+		`ALTER TABLE achievements ADD COLUMN IF NOT EXISTS series TEXT`,
+		`ALTER TABLE achievements ADD COLUMN IF NOT EXISTS tier TEXT`,
+		`ALTER TABLE achievements ADD COLUMN IF NOT EXISTS points INTEGER NOT NULL DEFAULT 10`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS achievements_series_tier_idx
+			ON achievements (series, tier)
+			WHERE series IS NOT NULL`,
 		`CREATE TABLE IF NOT EXISTS user_achievements (
 			user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 			achievement_id INTEGER NOT NULL REFERENCES achievements(id) ON DELETE CASCADE,
 			earned_at TIMESTAMP DEFAULT NOW(),
 			PRIMARY KEY (user_id, achievement_id)
 		)`,
+		`CREATE INDEX IF NOT EXISTS user_achievements_earned_idx
+			ON user_achievements (achievement_id, earned_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS achievement_stats (
+			achievement_id INTEGER PRIMARY KEY REFERENCES achievements(id) ON DELETE CASCADE,
+			earned_count INTEGER NOT NULL DEFAULT 0,
+			active_earned_count INTEGER NOT NULL DEFAULT 0,
+			active_players INTEGER NOT NULL DEFAULT 0,
+			earned_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+			first_earner JSONB,
+			recent_earners JSONB NOT NULL DEFAULT '[]'::jsonb,
+			refreshed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
 	}
 
 	for _, q := range queries {
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"game/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrOutboxEventNotFound = errors.New("outbox event not found")
+
+const outboxColumns = `
+	id, event_type, idempotency_key, payload, status, attempts,
+	next_attempt_at, locked_until, last_error, created_at, delivered_at
+`
+
+func (d *Database) AddOutboxEventTx(ctx context.Context, tx *sqlx.Tx, e *models.OutboxEvent) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO outbox_events (event_type, idempotency_key, payload)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, e.EventType, e.IdempotencyKey, e.Payload).Scan(&e.ID)
+	if err != nil {
+		return fmt.Errorf("add outbox event: %w", err)
+	}
+	return nil
+}
+
+// ClaimOutboxEvents забирает готовые к отправке события на время lease,
+// чтобы их не взял параллельный диспетчер
+func (d *Database) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	events := []models.OutboxEvent{}
+	err := d.DB.SelectContext(ctx, &events, `
+		UPDATE outbox_events
+		SET locked_until = NOW() + $2 * INTERVAL '1 millisecond', attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+outboxColumns, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", err)
+	}
+	return events, nil
+}
+
+// ClaimOutboxEvent забирает одно событие, если оно ещё ждёт отправки
+func (d *Database) ClaimOutboxEvent(ctx context.Context, id int64, lease time.Duration) (*models.OutboxEvent, error) {
+	var e models.OutboxEvent
+	err := d.DB.GetContext(ctx, &e, `
+		UPDATE outbox_events
+		SET locked_until = NOW() + $2 * INTERVAL '1 millisecond', attempts = attempts + 1
+		WHERE id = $1 AND status = 'pending'
+		  AND (locked_until IS NULL OR locked_until < NOW())
+		RETURNING `+outboxColumns, id, lease.Milliseconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox event: %w", err)
+	}
+	return &e, nil
+}
+
+func (d *Database) MarkOutboxDelivered(ctx context.Context, id int64) error {
+	_, err := d.DB.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET status = 'delivered', delivered_at = NOW(), locked_until = NULL, last_error = NULL
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("mark outbox event delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkOutboxFailed откладывает событие до nextAttemptAt или, если dead, переносит в dead-letter
+func (d *Database) MarkOutboxFailed(ctx context.Context, id int64, reason string, nextAttemptAt time.Time, dead bool) error {
+	status := models.OutboxStatusPending
+	if dead {
+		status = models.OutboxStatusDead
+	}
+	_, err := d.DB.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET status = $2, last_error = $3, next_attempt_at = $4, locked_until = NULL
+		WHERE id = $1
+	`, id, status, reason, nextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("mark outbox event failed: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetDeadOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	events := []models.OutboxEvent{}
+	err := d.DB.SelectContext(ctx, &events, `
+		SELECT `+outboxColumns+` FROM outbox_events
+		WHERE status = 'dead'
+		ORDER BY id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get dead outbox events: %w", err)
+	}
+	return events, nil
+}
+
+// RetryOutboxEvent возвращает событие из dead-letter в очередь с обнулёнными попытками
+func (d *Database) RetryOutboxEvent(ctx context.Context, id int64) error {
+	res, err := d.DB.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_until = NULL
+		WHERE id = $1 AND status = 'dead'
+	`, id)
+	if err != nil {
+		return fmt.Errorf("retry outbox event: %w", err)
+	}
+	return expectAffected(res, ErrOutboxEventNotFound)
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"game/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const sessionColumns = `
+	id, user_id, field_id, status, current_grid, pencil_marks,
+	started_at, resumed_at, elapsed_ms, checks_used, ranked, completed_at, updated_at
+`
+
+type sessionRow struct {
+	models.GameSession
+	RawPencilMarks []byte `db:"pencil_marks"`
+}
+
+func (r *sessionRow) toModel() (*models.GameSession, error) {
+	s := r.GameSession
+	s.PencilMarks = map[string]string{}
+	if len(r.RawPencilMarks) > 0 {
+		if err := json.Unmarshal(r.RawPencilMarks, &s.PencilMarks); err != nil {
+			return nil, fmt.Errorf("unmarshal pencil marks: %w", err)
+		}
+	}
+	return &s, nil
+}
+
+func (d *Database) CreateSession(ctx context.Context, s *models.GameSession) error {
+	return insertSession(ctx, d.DB, s)
+}
+
+// insertSession создаёт сессию и заполняет s.Ranked: в рекорды идёт только
+// первая сессия пользователя на поле, в остальных он уже видел поле
+func insertSession(ctx context.Context, db sqlx.QueryerContext, s *models.GameSession) error {
+	marks, err := json.Marshal(s.PencilMarks)
+	if err != nil {
+		return fmt.Errorf("marshal pencil marks: %w", err)
+	}
+
+	err = db.QueryRowxContext(ctx, `
+		INSERT INTO game_sessions (id, user_id, field_id, status, current_grid, pencil_marks, started_at, resumed_at, ranked)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8,
+		        NOT EXISTS (SELECT 1 FROM game_sessions WHERE user_id = $2 AND field_id = $3))
+		RETURNING ranked
+	`, s.ID, s.UserID, s.FieldID, s.Status, s.CurrentGrid, marks, s.StartedAt, s.ResumedAt).Scan(&s.Ranked)
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetSession(ctx context.Context, id string) (*models.GameSession, error) {
+	var row sessionRow
+	err := d.DB.GetContext(ctx, &row, `SELECT `+sessionColumns+` FROM game_sessions WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get session: %w", err)
+	}
+	return row.toModel()
+}
+
+// GetOpenSession возвращает незавершённую сессию пользователя по головоломке
+func (d *Database) GetOpenSession(ctx context.Context, userID, fieldID string) (*models.GameSession, error) {
+	var row sessionRow
+	err := d.DB.GetContext(ctx, &row, `
+		SELECT `+sessionColumns+` FROM game_sessions
+		WHERE user_id = $1 AND field_id = $2 AND status IN ('active', 'paused')
+	`, userID, fieldID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get open session: %w", err)
+	}
+	return row.toModel()
+}
+
+func (d *Database) GetOpenSessions(ctx context.Context, userID string) ([]models.GameSession, error) {
+	var rows []sessionRow
+	err := d.DB.SelectContext(ctx, &rows, `
+		SELECT `+sessionColumns+` FROM game_sessions
+		WHERE user_id = $1 AND status IN ('active', 'paused')
+		ORDER BY updated_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get open sessions: %w", err)
+	}
+
+	sessions := make([]models.GameSession, 0, len(rows))
+	for i := range rows {
+		s, err := rows[i].toModel()
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *s)
+	}
+	return sessions, nil
+}
+
+// LockSessionTx читает сессию с блокировкой строки до конца транзакции
+func (d *Database) LockSessionTx(ctx context.Context, tx *sqlx.Tx, id string) (*models.GameSession, error) {
+	var row sessionRow
+	err := tx.GetContext(ctx, &row, `SELECT `+sessionColumns+` FROM game_sessions WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock session: %w", err)
+	}
+	return row.toModel()
+}
+
+func (d *Database) SaveSessionTx(ctx context.Context, tx *sqlx.Tx, s *models.GameSession) error {
+	marks, err := json.Marshal(s.PencilMarks)
+	if err != nil {
+		return fmt.Errorf("marshal pencil marks: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE game_sessions
+		SET status = $2,
+		    current_grid = $3,
+		    pencil_marks = $4,
+		    resumed_at = $5,
+		    elapsed_ms = $6,
+		    checks_used = $7,
+		    completed_at = $8,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, s.ID, s.Status, s.CurrentGrid, marks, s.ResumedAt, s.ElapsedMs, s.ChecksUsed, s.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) AddMoveTx(ctx context.Context, tx *sqlx.Tx, m *models.GameMove) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO game_session_moves (session_id, cell, value, prev_value, pencil_marks, prev_pencil_marks)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, m.SessionID, m.Cell, m.Value, m.PrevValue, m.PencilMarks, m.PrevPencilMarks).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert move: %w", err)
+	}
+	return nil
+}
+
+// LastMoveTx возвращает последний неотменённый ход
+func (d *Database) LastMoveTx(ctx context.Context, tx *sqlx.Tx, sessionID string) (*models.GameMove, error) {
+	var m models.GameMove
+	err := tx.GetContext(ctx, &m, `
+		SELECT id, session_id, cell, value, prev_value, pencil_marks, prev_pencil_marks, undone, created_at
+		FROM game_session_moves
+		WHERE session_id = $1 AND NOT undone
+		ORDER BY id DESC
+		LIMIT 1
+	`, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get last move: %w", err)
+	}
+	return &m, nil
+}
+
+func (d *Database) MarkMoveUndoneTx(ctx context.Context, tx *sqlx.Tx, moveID int64) error {
+	_, err := tx.ExecContext(ctx, `UPDATE game_session_moves SET undone = TRUE WHERE id = $1`, moveID)
+	if err != nil {
+		return fmt.Errorf("mark move undone: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetSessionMoves(ctx context.Context, sessionID string) ([]models.GameMove, error) {
+	moves := []models.GameMove{}
+	err := d.DB.SelectContext(ctx, &moves, `
+		SELECT id, session_id, cell, value, prev_value, pencil_marks, prev_pencil_marks, undone, created_at
+		FROM game_session_moves
+		WHERE session_id = $1
+		ORDER BY id
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("get session moves: %w", err)
+	}
+	return moves, nil
+}
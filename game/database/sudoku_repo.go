@@ -5,22 +5,92 @@ import (
 	"database/sql"
 	"fmt"
 	"game/models"
+	"math/rand"
 
-	_ "github.com/lib/pq"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
-func (d *Database) GetRandomByComplexity(ctx context.Context, complexity string) (*models.SudokuField, error) {
-	const query = `
-		SELECT id, initial_field, solution, complexity, created_at,
-		       solve_attempts, solves_successful, solves_total_time
-		FROM sudoku_fields
-		WHERE complexity = $1
-		ORDER BY RANDOM()
+// tagFilter оставляет только поля, у которых есть все перечисленные теги
+const tagFilter = `
+		AND (COALESCE(cardinality($2::text[]), 0) = 0 OR f.id IN (
+			SELECT ft.field_id
+			FROM sudoku_field_tags ft
+			JOIN sudoku_tags t ON t.id = ft.tag_id
+			WHERE t.name = ANY($2)
+			GROUP BY ft.field_id
+			HAVING COUNT(DISTINCT t.name) = cardinality($2::text[])
+		))
+`
+
+const fieldColumns = `
+		f.id, f.initial_field, f.solution, f.complexity, f.created_at,
+		f.solve_attempts, f.solves_successful, f.solves_total_time`
+
+// PickSudoku выбирает поле для пользователя. В первую очередь — случайное из тех,
+// что ему ещё не выдавались (по random_key с индексом вместо ORDER BY RANDOM()).
+// Если таких не осталось — давно выданное нерешённое, затем давно решённое.
+// solved сообщает, что пользователь уже решал выбранное поле.
+func (d *Database) PickSudoku(ctx context.Context, userID, complexity string, tags []string) (field *models.SudokuField, solved bool, err error) {
+	const unseen = `
+		SELECT` + fieldColumns + `
+		FROM sudoku_fields f
+		WHERE f.complexity = $1` + tagFilter + `
+		  AND NOT EXISTS (
+			SELECT 1 FROM user_sudoku_history h
+			WHERE h.user_id = $3 AND h.field_id = f.id
+		  )
+		  AND f.random_key %s $4
+		ORDER BY f.random_key %s
 		LIMIT 1
 	`
 
-	row := d.DB.QueryRowContext(ctx, query, complexity)
+	defer func() {
+		if field != nil {
+			go d.incrementAttempts(context.Background(), field.ID)
+		}
+	}()
 
+	key := rand.Float64()
+	for _, order := range [][2]string{{">=", "ASC"}, {"<", "DESC"}} {
+		field, err = scanField(d.DB.QueryRowContext(ctx, fmt.Sprintf(unseen, order[0], order[1]),
+			complexity, pq.Array(tags), userID, key))
+		if err != nil || field != nil {
+			return field, false, err
+		}
+	}
+
+	const seen = `
+		SELECT` + fieldColumns + `, h.solved_at IS NOT NULL
+		FROM sudoku_fields f
+		JOIN user_sudoku_history h ON h.field_id = f.id AND h.user_id = $3
+		WHERE f.complexity = $1` + tagFilter + `
+		ORDER BY h.solved_at IS NOT NULL, h.last_seen_at
+		LIMIT 1
+	`
+
+	var f models.SudokuField
+	err = d.DB.QueryRowContext(ctx, seen, complexity, pq.Array(tags), userID).Scan(
+		&f.ID,
+		&f.InitialField,
+		&f.Solution,
+		&f.Complexity,
+		&f.CreatedAt,
+		&f.SolveAttempts,
+		&f.SolvesSuccessful,
+		&f.SolvesTotalTime,
+		&solved,
+	)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("scan seen sudoku: %w", err)
+	}
+	return &f, solved, nil
+}
+
+func scanField(row *sql.Row) (*models.SudokuField, error) {
 	var field models.SudokuField
 	err := row.Scan(
 		&field.ID,
@@ -38,9 +108,6 @@ func (d *Database) GetRandomByComplexity(ctx context.Context, complexity string)
 	if err != nil {
 		return nil, fmt.Errorf("scan sudoku: %w", err)
 	}
-
-	go d.incrementAttempts(context.Background(), field.ID)
-
 	return &field, nil
 }
 
@@ -48,17 +115,16 @@ func (d *Database) incrementAttempts(ctx context.Context, id string) {
 	const update = `UPDATE sudoku_fields SET solve_attempts = solve_attempts + 1 WHERE id = $1`
 	_, _ = d.DB.ExecContext(ctx, update, id)
 }
-
-func (d *Database) GetFieldsByComplexity(ctx context.Context, difficulty string) ([]models.SudokuField, error) {
+func (d *Database) GetFieldsByComplexity(ctx context.Context, difficulty string, tags []string) ([]models.SudokuField, error) {
 	const query = `
 		SELECT id, initial_field, solution, complexity, created_at,
 		       solve_attempts, solves_successful, solves_total_time
-		FROM sudoku_fields
-		WHERE complexity = $1
+		FROM sudoku_fields f
+		WHERE complexity = $1` + tagFilter + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := d.DB.QueryContext(ctx, query, difficulty)
+	rows, err := d.DB.QueryContext(ctx, query, difficulty, pq.Array(tags))
 	if err != nil {
 		return nil, fmt.Errorf("query fields: %w", err)
 	}
@@ -114,7 +180,8 @@ func (d *Database) GetSudokuByID(ctx context.Context, id string) (*models.Sudoku
 	return &field, nil
 }
 
-func (d *Database) MarkSudokuSolved(ctx context.Context, id string, solveTimeMs int64) error {
+// MarkSudokuSolvedTx обновляет статистику поля и историю решений пользователя
+func (d *Database) MarkSudokuSolvedTx(ctx context.Context, tx *sqlx.Tx, id, userID string, solveTimeMs int64) error {
 	const query = `
 		UPDATE sudoku_fields
 		SET solves_successful = solves_successful + 1,
@@ -122,11 +189,10 @@ func (d *Database) MarkSudokuSolved(ctx context.Context, id string, solveTimeMs
 		WHERE id = $1
 	`
 
-	_, err := d.DB.ExecContext(ctx, query, id, solveTimeMs)
-	if err != nil {
+	if _, err := tx.ExecContext(ctx, query, id, solveTimeMs); err != nil {
 		return fmt.Errorf("update solved stats: %w", err)
 	}
-	return nil
+	return recordSolveTx(ctx, tx, userID, id, solveTimeMs)
 }
 
 func (d *Database) GetSudokuDifficultyByID(ctx context.Context, sudokuID string) (string, error) {
@@ -144,3 +210,82 @@ func (d *Database) GetSudokuDifficultyByID(ctx context.Context, sudokuID string)
 
 	return difficulty, nil
 }
+
+// GetSudokuForVerification возвращает поле без увеличения счётчика попыток
+func (d *Database) GetSudokuForVerification(ctx context.Context, id string) (*models.SudokuField, error) {
+	const query = `
+		SELECT id, initial_field, solution, complexity
+		FROM sudoku_fields
+		WHERE id = $1
+	`
+
+	var field models.SudokuField
+	err := d.DB.QueryRowContext(ctx, query, id).Scan(
+		&field.ID,
+		&field.InitialField,
+		&field.Solution,
+		&field.Complexity,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get sudoku for verification: %w", err)
+	}
+	return &field, nil
+}
+
+// InsertSudokuField добавляет поле вместе с тегами (недостающие теги создаются);
+// false — такое поле уже есть
+func (d *Database) InsertSudokuField(ctx context.Context, field *models.SudokuField, tags []string) (bool, error) {
+	const query = `
+		INSERT INTO sudoku_fields (id, initial_field, solution, complexity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (initial_field, solution) DO NOTHING
+	`
+
+	inserted := false
+	err := d.WithTx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, query, field.ID, field.InitialField, field.Solution, field.Complexity)
+		if err != nil {
+			return fmt.Errorf("insert sudoku field: %w", err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("rows affected: %w", err)
+		}
+		if affected == 0 {
+			return nil
+		}
+		inserted = true
+
+		ids, err := ensureTagsTx(ctx, tx, tags)
+		if err != nil {
+			return err
+		}
+		return linkTagsTx(ctx, tx, field.ID, ids)
+	})
+	return inserted, err
+}
+
+func (d *Database) CountFieldsByComplexity(ctx context.Context) (map[string]int, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT complexity, COUNT(*) FROM sudoku_fields GROUP BY complexity
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("count fields: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var complexity string
+		var count int
+		if err := rows.Scan(&complexity, &count); err != nil {
+			return nil, fmt.Errorf("scan count: %w", err)
+		}
+		counts[complexity] = count
+	}
+	return counts, nil
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"game/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrTagNotFound = errors.New("tag not found")
+
+func (d *Database) GetAllTags(ctx context.Context) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	if err := d.DB.SelectContext(ctx, &tags, `SELECT id, name FROM sudoku_tags ORDER BY name`); err != nil {
+		return nil, fmt.Errorf("get tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (d *Database) CreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	tag := &models.Tag{ID: uuid.New().String(), Name: name}
+	_, err := d.DB.ExecContext(ctx, `INSERT INTO sudoku_tags (id, name) VALUES ($1, $2)`, tag.ID, tag.Name)
+	if err != nil {
+		return nil, fmt.Errorf("create tag: %w", err)
+	}
+	return tag, nil
+}
+
+func (d *Database) RenameTag(ctx context.Context, name, newName string) error {
+	res, err := d.DB.ExecContext(ctx, `UPDATE sudoku_tags SET name = $2 WHERE name = $1`, name, newName)
+	if err != nil {
+		return fmt.Errorf("rename tag: %w", err)
+	}
+	return expectAffected(res, ErrTagNotFound)
+}
+
+func (d *Database) DeleteTag(ctx context.Context, name string) error {
+	res, err := d.DB.ExecContext(ctx, `DELETE FROM sudoku_tags WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("delete tag: %w", err)
+	}
+	return expectAffected(res, ErrTagNotFound)
+}
+
+// TagField привязывает к полю существующие теги
+func (d *Database) TagField(ctx context.Context, fieldID string, names []string) error {
+	return d.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var ids []string
+		if err := tx.SelectContext(ctx, &ids, `SELECT id FROM sudoku_tags WHERE name = ANY($1)`, pq.Array(names)); err != nil {
+			return fmt.Errorf("get tag ids: %w", err)
+		}
+		if len(ids) != len(uniqueStrings(names)) {
+			return ErrTagNotFound
+		}
+		return linkTagsTx(ctx, tx, fieldID, ids)
+	})
+}
+
+func (d *Database) UntagField(ctx context.Context, fieldID, name string) error {
+	res, err := d.DB.ExecContext(ctx, `
+		DELETE FROM sudoku_field_tags
+		WHERE field_id = $1 AND tag_id = (SELECT id FROM sudoku_tags WHERE name = $2)
+	`, fieldID, name)
+	if err != nil {
+		return fmt.Errorf("untag field: %w", err)
+	}
+	return expectAffected(res, ErrTagNotFound)
+}
+
+// GetTagsForFields возвращает теги для набора полей: id поля -> имена тегов
+func (d *Database) GetTagsForFields(ctx context.Context, fieldIDs []string) (map[string][]string, error) {
+	result := make(map[string][]string, len(fieldIDs))
+	if len(fieldIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT ft.field_id, t.name
+		FROM sudoku_field_tags ft
+		JOIN sudoku_tags t ON t.id = ft.tag_id
+		WHERE ft.field_id = ANY($1)
+		ORDER BY t.name
+	`, pq.Array(fieldIDs))
+	if err != nil {
+		return nil, fmt.Errorf("get field tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fieldID, name string
+		if err := rows.Scan(&fieldID, &name); err != nil {
+			return nil, fmt.Errorf("scan field tag: %w", err)
+		}
+		result[fieldID] = append(result[fieldID], name)
+	}
+	return result, nil
+}
+
+// ensureTagsTx создаёт недостающие теги и возвращает id всех переданных
+func ensureTagsTx(ctx context.Context, tx *sqlx.Tx, names []string) ([]string, error) {
+	ids := make([]string, 0, len(names))
+	for _, name := range uniqueStrings(names) {
+		var id string
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO sudoku_tags (id, name) VALUES ($1, $2)
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id
+		`, uuid.New().String(), name).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("ensure tag %s: %w", name, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func linkTagsTx(ctx context.Context, tx *sqlx.Tx, fieldID string, tagIDs []string) error {
+	for _, id := range tagIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO sudoku_field_tags (field_id, tag_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, fieldID, id)
+		if err != nil {
+			return fmt.Errorf("link tag: %w", err)
+		}
+	}
+	return nil
+}
+
+func expectAffected(res sql.Result, notFound error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -122,3 +122,20 @@ func (d *Database) DeleteUserAchievement(ctx context.Context, userID string, cod
 
 	return nil
 }
+
+// GetUserIDsWithoutAchievement возвращает пользователей, у которых ещё нет достижения
+func (d *Database) GetUserIDsWithoutAchievement(ctx context.Context, code string) ([]string, error) {
+	var ids []string
+	err := d.DB.SelectContext(ctx, &ids, `
+		SELECT u.id FROM users u
+		WHERE NOT EXISTS (
+			SELECT 1 FROM user_achievements ua
+			JOIN achievements a ON a.id = ua.achievement_id
+			WHERE ua.user_id = u.id AND a.code = $1
+		)
+	`, code)
+	if err != nil {
+		return nil, fmt.Errorf("get users without achievement: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,151 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"game/models"
+	"game/sudoku"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownDifficulty = errors.New("unknown difficulty")
+	ErrAttemptsExhausted = errors.New("failed to generate puzzle of requested difficulty")
+)
+
+const (
+	defaultMaxAttempts = 200
+	// Сколько раз попытка возвращает несколько клеток и убирает их заново,
+	// если поле вышло проще целевой сложности
+	refineRounds  = 20
+	refineRestore = 3
+)
+
+// Difficulties — уровни сложности, которые умеет строить генератор
+var Difficulties = sudoku.Difficulties
+
+// Puzzle — сгенерированная головоломка с единственным решением
+type Puzzle struct {
+	Initial  sudoku.Grid
+	Solution sudoku.Grid
+	Grade    sudoku.Analysis
+}
+
+// Generator безопасен для параллельного использования: под мьютексом
+// берётся только зерно для генератора случайных чисел попытки
+type Generator struct {
+	mu          sync.Mutex
+	rnd         *rand.Rand
+	maxAttempts int
+}
+
+func New() *Generator {
+	return &Generator{
+		rnd:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		maxAttempts: defaultMaxAttempts,
+	}
+}
+
+// Generate строит головоломку заданной сложности. Клетки убираются
+// симметричными парами, затем по одной, пока решение остаётся единственным,
+// а оценка не превышает целевую. Если поле вышло проще, несколько клеток
+// возвращаются и убираются заново, пока оценка не дойдёт до целевой;
+// не помогло — попытка повторяется с новым решением.
+func (g *Generator) Generate(difficulty string) (*Puzzle, error) {
+	target, ok := sudoku.DifficultyLevel(difficulty)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDifficulty, difficulty)
+	}
+
+	for attempt := 0; attempt < g.maxAttempts; attempt++ {
+		if p := g.attempt(g.newRand(), target); p != nil {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAttemptsExhausted, difficulty)
+}
+
+func (g *Generator) newRand() *rand.Rand {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return rand.New(rand.NewSource(g.rnd.Int63()))
+}
+
+func (g *Generator) attempt(rnd *rand.Rand, target int) *Puzzle {
+	solution := sudoku.RandomSolved(rnd)
+	puzzle := solution
+
+	for _, cell := range rnd.Perm(sudoku.CellCount/2 + 1) {
+		tryRemove(&puzzle, target, cell, sudoku.CellCount-1-cell)
+	}
+	// Симметричные пары часто не дают убрать последние клетки
+	removeSingles(rnd, &puzzle, target)
+	level := gradeLevel(puzzle)
+
+	// Продолжаем от лучшего поля: возвращаем несколько клеток и убираем
+	// в другом порядке, не давая оценке упасть
+	for round := 0; round < refineRounds && level < target; round++ {
+		candidate := puzzle
+		for restored, i := 0, 0; restored < refineRestore && i < sudoku.CellCount; i++ {
+			if cell := rnd.Intn(sudoku.CellCount); candidate[cell] == 0 {
+				candidate[cell] = solution[cell]
+				restored++
+			}
+		}
+		removeSingles(rnd, &candidate, target)
+		if l := gradeLevel(candidate); l >= level {
+			puzzle, level = candidate, l
+		}
+	}
+
+	grade := sudoku.Analyze(puzzle)
+	if grade.Difficulty != sudoku.Difficulties[target] {
+		return nil
+	}
+	grade.Steps = nil
+
+	return &Puzzle{Initial: puzzle, Solution: solution, Grade: grade}
+}
+
+func removeSingles(rnd *rand.Rand, puzzle *sudoku.Grid, target int) {
+	for _, cell := range rnd.Perm(sudoku.CellCount) {
+		if puzzle[cell] != 0 {
+			tryRemove(puzzle, target, cell, cell)
+		}
+	}
+}
+
+// tryRemove убирает клетку и её зеркальную пару, если решение остаётся
+// единственным, а оценка не превышает целевую
+func tryRemove(puzzle *sudoku.Grid, target, cell, mirror int) {
+	saved, savedMirror := puzzle[cell], puzzle[mirror]
+	puzzle[cell], puzzle[mirror] = 0, 0
+	if sudoku.CountSolutions(*puzzle, 2) != 1 || gradeLevel(*puzzle) > target {
+		puzzle[cell], puzzle[mirror] = saved, savedMirror
+	}
+}
+
+func gradeLevel(puzzle sudoku.Grid) int {
+	level, _ := sudoku.DifficultyLevel(sudoku.Analyze(puzzle).Difficulty)
+	return level
+}
+
+// Field готовит строку sudoku_fields для сохранения
+func (p *Puzzle) Field() models.SudokuField {
+	return models.SudokuField{
+		ID:           uuid.New().String(),
+		InitialField: p.Initial.String(),
+		Solution:     p.Solution.String(),
+		Complexity:   p.Grade.Difficulty,
+	}
+}
+
+// Tags возвращает автоматические теги головоломки
+func (p *Puzzle) Tags() []string {
+	return sudoku.AutoTags(p.Initial, p.Grade)
+}
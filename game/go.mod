@@ -4,6 +4,8 @@ go 1.24.0
 
 require github.com/gin-gonic/gin v1.10.0
 
+require github.com/golang-jwt/jwt/v5 v5.2.1
+
 require (
 	github.com/bytedance/sonic v1.11.6 // indirect
 	github.com/bytedance/sonic/loader v0.1.1 // indirect
@@ -37,3 +39,7 @@ require (
 	google.golang.org/protobuf v1.34.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+require servicetoken v0.0.0
+
+replace servicetoken => ../servicetoken
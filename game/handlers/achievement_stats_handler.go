@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAchievementStats возвращает сколько игроков получили каждое достижение
+func (h *GameHandler) GetAchievementStats(c *gin.Context) {
+	stats, err := h.db.GetAchievementStats(c.Request.Context())
+	if err != nil {
+		h.logger.Errorf("failed to fetch achievement stats: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch achievement stats"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *GameHandler) GetAchievementStatsByCode(c *gin.Context) {
+	code := c.Param("code")
+
+	stats, err := h.db.GetAchievementStatsByCode(c.Request.Context(), code)
+	if err != nil {
+		h.logger.Errorf("failed to fetch stats for achievement %s: %v", code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch achievement stats"})
+		return
+	}
+	if stats == nil {
+		// Достижения нет или агрегат ещё не пересчитан
+		c.JSON(http.StatusNotFound, gin.H{"error": "achievement stats not found"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"game/models"
 	"io"
 	"mime"
@@ -55,6 +57,33 @@ func (h *GameHandler) CreateAchievement(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid condition JSON"})
 		return
 	}
+	if err := h.issuer.ValidateCondition(cond); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	points := models.DefaultAchievementPoints
+	if form.Points != nil {
+		points = *form.Points
+	}
+	if points < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "points must not be negative"})
+		return
+	}
+
+	// Собираем структуру
+	achievement := models.Achievement{
+		Code:        form.Code,
+		Title:       form.Title,
+		Description: form.Description,
+		Condition:   cond,
+		Series:      form.Series,
+		Tier:        form.Tier,
+		Points:      points,
+	}
+	if !h.checkSeries(c, achievement) {
+		return
+	}
 
 	// Загружаем иконку
 	file, header, err := c.Request.FormFile("icon")
@@ -84,14 +113,7 @@ func (h *GameHandler) CreateAchievement(c *gin.Context) {
 		return
 	}
 
-	// Собираем структуру
-	achievement := models.Achievement{
-		Code:        form.Code,
-		Title:       form.Title,
-		Description: form.Description,
-		IconURL:     filename,
-		Condition:   cond,
-	}
+	achievement.IconURL = filename
 
 	if err := h.db.InsertAchievement(c.Request.Context(), achievement); err != nil {
 		h.logger.Errorf("failed to insert achievement: %v", err)
@@ -106,11 +128,16 @@ func (h *GameHandler) CreateAchievement(c *gin.Context) {
 	}
 
 	h.logger.Infof("achievement created: %s", form.Code)
+	h.issuer.ReevaluateAsync(achievement.Code)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"code":        achievement.Code,
 		"title":       achievement.Title,
 		"description": achievement.Description,
 		"icon_url":    achievement.IconURL,
+		"series":      achievement.Series,
+		"tier":        achievement.Tier,
+		"points":      achievement.Points,
 	})
 }
 
@@ -129,6 +156,48 @@ func (h *GameHandler) UpdateAchievement(c *gin.Context) {
 		return
 	}
 
+	current, err := h.db.GetAchievementByCode(code)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Achievement not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to fetch achievement: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch achievement"})
+		return
+	}
+
+	if form.Condition != "" {
+		var cond models.AchievementCondition
+		if err := json.Unmarshal([]byte(form.Condition), &cond); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid condition JSON"})
+			return
+		}
+		if err := h.issuer.ValidateCondition(cond); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		current.Condition = cond
+	}
+	if form.Points != nil {
+		if *form.Points < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "points must not be negative"})
+			return
+		}
+		current.Points = *form.Points
+	}
+
+	// Уровень проверяем в том виде, каким он станет после обновления
+	if form.Series != "" {
+		current.Series = form.Series
+	}
+	if form.Tier != "" {
+		current.Tier = form.Tier
+	}
+	if !h.checkSeries(c, current) {
+		return
+	}
+
 	// Получаем старую иконку
 	oldIcon, err := h.db.GetAchievementIconFilename(code)
 	if err != nil {
@@ -162,7 +231,7 @@ func (h *GameHandler) UpdateAchievement(c *gin.Context) {
 	}
 
 	// Обновляем в БД, включая условие
-	err = h.db.UpdateAchievement(c.Request.Context(), code, form.Title, form.Description, filename, form.Condition)
+	err = h.db.UpdateAchievement(c.Request.Context(), code, filename, form)
 	if err != nil {
 		h.logger.Errorf("failed to update achievement: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update achievement"})
@@ -178,6 +247,10 @@ func (h *GameHandler) UpdateAchievement(c *gin.Context) {
 	}
 
 	h.logger.Infof("achievement updated: %s", code)
+	if form.Condition != "" {
+		h.issuer.ReevaluateAsync(code)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
 }
 
@@ -246,3 +319,34 @@ func (h *GameHandler) GetAchievementIcon(c *gin.Context) {
 	c.Header("Content-Type", contentType)
 	c.File(filePath)
 }
+
+// checkSeries проверяет уровень достижения вместе с остальными уровнями его серии;
+// при ошибке отвечает сам и возвращает false
+func (h *GameHandler) checkSeries(c *gin.Context, a models.Achievement) bool {
+	if a.Series == "" && a.Tier == "" {
+		return true
+	}
+	if a.Series == "" || a.Tier == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "series and tier must be set together"})
+		return false
+	}
+
+	siblings, err := h.db.GetAchievementsBySeries(c.Request.Context(), a.Series)
+	if err != nil {
+		h.logger.Errorf("failed to fetch achievement series %s: %v", a.Series, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch achievement series"})
+		return false
+	}
+
+	tiers := []models.Achievement{a}
+	for _, s := range siblings {
+		if s.Code != a.Code {
+			tiers = append(tiers, s)
+		}
+	}
+	if err := h.issuer.ValidateSeries(tiers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
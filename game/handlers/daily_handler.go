@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"game/database"
+	"game/models"
+	"game/sudoku"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	dateLayout              = "2006-01-02"
+	defaultLeaderboardLimit = 50
+	maxLeaderboardLimit     = 100
+
+	// Попытка дня быстрее этого в рейтинг не попадает: так решают только
+	// поле, полученное заранее, например с другого аккаунта
+	minDailySolveTime = 60 * time.Second
+)
+
+// GetDaily возвращает состояние головоломки дня для пользователя.
+// Само поле выдаётся только при старте попытки, чтобы время шло с первого взгляда на него.
+func (h *GameHandler) GetDaily(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	difficulty, ok := dailyDifficulty(c, c.Query("difficulty"))
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	today := dailyDate(time.Now())
+
+	field, err := h.db.EnsureDailyPuzzle(ctx, today, difficulty, dailyKey(today, difficulty))
+	if err != nil {
+		h.logger.Errorf("failed to get daily %s sudoku: %v", difficulty, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get daily sudoku"})
+		return
+	}
+	if field == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "нет доступных судоку"})
+		return
+	}
+
+	attempt, err := h.db.GetDailyAttempt(ctx, today, difficulty, userID)
+	if err != nil {
+		h.logger.Errorf("failed to get daily attempt of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get daily attempt"})
+		return
+	}
+
+	resp := models.DailyResponse{
+		Date:       today.Format(dateLayout),
+		Difficulty: difficulty,
+		Attempt:    attempt,
+	}
+
+	if attempt != nil && attempt.ElapsedMs != nil && attempt.Ranked {
+		rank, err := h.db.GetDailyRank(ctx, today, difficulty, *attempt.ElapsedMs)
+		if err != nil {
+			h.logger.Errorf("failed to get daily rank of user %s: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get daily rank"})
+			return
+		}
+		resp.Rank = &rank
+	}
+
+	resp.Streak, err = h.dailyStreak(c, userID, today)
+	if err != nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// StartDaily начинает единственную зачётную попытку на головоломку дня.
+// Попытка — обычная игровая сессия, завершение которой засчитывается в рейтинг дня.
+func (h *GameHandler) StartDaily(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.DailyStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	difficulty, ok := dailyDifficulty(c, req.Difficulty)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	today := dailyDate(time.Now())
+
+	field, err := h.db.EnsureDailyPuzzle(ctx, today, difficulty, dailyKey(today, difficulty))
+	if err != nil {
+		h.logger.Errorf("failed to get daily %s sudoku: %v", difficulty, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get daily sudoku"})
+		return
+	}
+	if field == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "нет доступных судоку"})
+		return
+	}
+
+	attempt, err := h.db.GetDailyAttempt(ctx, today, difficulty, userID)
+	if err != nil {
+		h.logger.Errorf("failed to get daily attempt of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get daily attempt"})
+		return
+	}
+	if attempt != nil {
+		h.respondDailyAttempt(c, attempt, field)
+		return
+	}
+
+	// Открытая сессия на этом поле становится попыткой дня вместе с уже набежавшим временем
+	session, err := h.db.GetOpenSession(ctx, userID, field.ID)
+	if err != nil {
+		h.logger.Errorf("failed to get open session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get session"})
+		return
+	}
+
+	now := time.Now().UTC()
+	newSession := session == nil
+	if newSession {
+		session = &models.GameSession{
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			FieldID:     field.ID,
+			Status:      models.SessionStatusActive,
+			CurrentGrid: field.InitialField,
+			PencilMarks: map[string]string{},
+			StartedAt:   now,
+			ResumedAt:   &now,
+		}
+	}
+
+	attempt = &models.DailyAttempt{
+		Date:       today,
+		Difficulty: difficulty,
+		UserID:     userID,
+		SessionID:  session.ID,
+		StartedAt:  now,
+	}
+
+	err = h.db.StartDailyAttempt(ctx, attempt, session, newSession)
+	if err == nil && session.Status == models.SessionStatusPaused {
+		session.Status = models.SessionStatusActive
+		session.ResumedAt = &now
+	}
+	if errors.Is(err, database.ErrDailyAttempted) {
+		// Параллельный запрос успел начать попытку раньше
+		existing, getErr := h.db.GetDailyAttempt(ctx, today, difficulty, userID)
+		if getErr == nil && existing != nil {
+			h.respondDailyAttempt(c, existing, field)
+			return
+		}
+		if getErr != nil {
+			err = getErr
+		}
+	}
+	if err != nil {
+		h.logger.Errorf("failed to start daily attempt of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start daily attempt"})
+		return
+	}
+
+	if err := h.db.MarkSudokuSeen(ctx, userID, field.ID); err != nil {
+		h.logger.Warnf("failed to mark sudoku %s seen by user %s: %v", field.ID, userID, err)
+	}
+
+	h.logger.Infof("daily %s attempt started by user %s in session %s", difficulty, userID, session.ID)
+	c.JSON(http.StatusCreated, h.sessionResponse(session, field))
+}
+
+// respondDailyAttempt продолжает начатую попытку или сообщает, что она уже сыграна
+func (h *GameHandler) respondDailyAttempt(c *gin.Context, attempt *models.DailyAttempt, field *models.SudokuField) {
+	session, err := h.db.GetSession(c.Request.Context(), attempt.SessionID)
+	if err != nil {
+		h.logger.Errorf("failed to get session %s: %v", attempt.SessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get session"})
+		return
+	}
+	if session == nil || !session.IsOpen() {
+		c.JSON(http.StatusConflict, gin.H{"error": "daily puzzle already attempted", "attempt": attempt})
+		return
+	}
+	c.JSON(http.StatusOK, h.sessionResponse(session, field))
+}
+
+func (h *GameHandler) GetDailyLeaderboard(c *gin.Context) {
+	difficulty, ok := dailyDifficulty(c, c.Query("difficulty"))
+	if !ok {
+		return
+	}
+
+	today := dailyDate(time.Now())
+	date := today
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.Parse(dateLayout, raw)
+		if err != nil || parsed.After(today) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD and not in the future"})
+			return
+		}
+		date = parsed
+	}
+
+	limit := defaultLeaderboardLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.db.GetDailyLeaderboard(c.Request.Context(), date, difficulty, limit)
+	if err != nil {
+		h.logger.Errorf("failed to get daily leaderboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DailyLeaderboardResponse{
+		Date:       date.Format(dateLayout),
+		Difficulty: difficulty,
+		Entries:    entries,
+	})
+}
+
+func (h *GameHandler) GetDailyStreak(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	streak, err := h.dailyStreak(c, userID, dailyDate(time.Now()))
+	if err != nil {
+		return
+	}
+	c.JSON(http.StatusOK, streak)
+}
+
+// dailyStreak читает серию; если вчерашняя головоломка не решена, серия прервана
+func (h *GameHandler) dailyStreak(c *gin.Context, userID string, today time.Time) (models.DailyStreak, error) {
+	streak, err := h.db.GetDailyStreak(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("failed to get daily streak of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get streak"})
+		return streak, err
+	}
+	if streak.LastDate == nil || streak.LastDate.Before(today.AddDate(0, 0, -1)) {
+		streak.Current = 0
+	}
+	return streak, nil
+}
+
+func dailyDifficulty(c *gin.Context, difficulty string) (string, bool) {
+	if difficulty == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "difficulty is required"})
+		return "", false
+	}
+	if _, ok := sudoku.DifficultyLevel(difficulty); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown difficulty"})
+		return "", false
+	}
+	return difficulty, true
+}
+
+// dailyDate — календарный день по UTC, одинаковый для всех игроков
+func dailyDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// dailyKey детерминированно отображает дату и сложность в [0, 1)
+func dailyKey(date time.Time, difficulty string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(date.Format(dateLayout) + ":" + difficulty))
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"game/generator"
+	"game/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxGenerateCount = 50
+
+func (h *GameHandler) GenerateSudoku(c *gin.Context) {
+	var req models.GenerateSudokuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.Count <= 0 {
+		req.Count = 1
+	}
+	if req.Count > maxGenerateCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must not exceed 50"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp := models.GenerateSudokuResponse{Generated: make([]models.GeneratedSudoku, 0, req.Count)}
+
+	for i := 0; i < req.Count; i++ {
+		puzzle, err := h.gen.Generate(req.Difficulty)
+		if errors.Is(err, generator.ErrUnknownDifficulty) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown difficulty"})
+			return
+		}
+		if err != nil {
+			h.logger.Warnf("failed to generate %s sudoku: %v", req.Difficulty, err)
+			break
+		}
+
+		field := puzzle.Field()
+		ok, err := h.db.InsertSudokuField(ctx, &field, puzzle.Tags())
+		if err != nil {
+			h.logger.Errorf("failed to save generated sudoku: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save sudoku"})
+			return
+		}
+		if !ok {
+			resp.Duplicates++
+			continue
+		}
+
+		resp.Generated = append(resp.Generated, models.GeneratedSudoku{
+			ID:         field.ID,
+			Complexity: field.Complexity,
+			Techniques: puzzle.Grade.Techniques,
+			Tags:       puzzle.Tags(),
+		})
+	}
+
+	// 503 — только если генератор ничего не построил; одни повторы — не сбой
+	if len(resp.Generated) == 0 && resp.Duplicates == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to generate sudoku of requested difficulty, try again"})
+		return
+	}
+
+	h.logger.Infof("generated %d %s sudoku, %d duplicates skipped", len(resp.Generated), req.Difficulty, resp.Duplicates)
+	status := http.StatusCreated
+	if len(resp.Generated) == 0 {
+		status = http.StatusOK
+	}
+	c.JSON(status, resp)
+}
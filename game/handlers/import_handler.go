@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"game/puzzleio"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ограничения одного запроса импорта: каждая головоломка решается и оценивается
+// синхронно, так что большие сборники нужно присылать частями
+const (
+	maxImportBytes   = 8 << 20
+	maxImportRecords = 5000
+)
+
+func (h *GameHandler) ImportSudoku(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	var body io.Reader = c.Request.Body
+	format := c.Query("format")
+
+	// Файл можно прислать как multipart-поле "file" или сырым телом запроса
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			h.respondImportReadError(c, fmt.Errorf("multipart field \"file\": %w", err))
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = puzzleio.FormatFromFilename(header.Filename)
+		}
+	}
+
+	records, err := puzzleio.Read(body, format)
+	if err != nil {
+		h.respondImportReadError(c, err)
+		return
+	}
+	if len(records) > maxImportRecords {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("at most %d puzzles per request", maxImportRecords)})
+		return
+	}
+
+	report, err := puzzleio.Import(c.Request.Context(), h.db, records, c.Query("difficulty"))
+	if err != nil {
+		h.logger.Errorf("sudoku import failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "import failed", "report": report})
+		return
+	}
+
+	h.logger.Infof("sudoku import: %d imported, %d duplicates, %d invalid", report.Imported, report.Duplicates, report.Invalid)
+	c.JSON(http.StatusOK, report)
+}
+
+// respondImportReadError: слишком большое тело — 413, остальные ошибки разбора — 400
+func (h *GameHandler) respondImportReadError(c *gin.Context, err error) {
+	h.logger.Warnf("failed to read import file: %v", err)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("import file must not exceed %d MiB", maxImportBytes>>20)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+func (h *GameHandler) ExportSudoku(c *gin.Context) {
+	difficulty := c.Query("difficulty")
+	if difficulty == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "difficulty is required"})
+		return
+	}
+	format := c.DefaultQuery("format", puzzleio.FormatLine)
+	if !puzzleio.IsFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be line, sdk or csv"})
+		return
+	}
+
+	fields, err := h.db.GetFieldsByComplexity(c.Request.Context(), difficulty, tagsQuery(c))
+	if err != nil {
+		h.logger.Errorf("failed to get sudokus for export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sudokus by difficulty"})
+		return
+	}
+
+	filename := fmt.Sprintf("sudoku-%s-%s.%s", difficulty, time.Now().Format("20060102"), format)
+	c.Header("Content-Type", puzzleio.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if err := puzzleio.Write(c.Writer, format, puzzleio.FromFields(fields)); err != nil {
+		h.logger.Errorf("failed to write export: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"game/database"
+	"game/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxDeadOutboxEvents = 200
+
+// GetDeadOutboxEvents показывает события, которые не удалось доставить
+func (h *GameHandler) GetDeadOutboxEvents(c *gin.Context) {
+	events, err := h.db.GetDeadOutboxEvents(c.Request.Context(), maxDeadOutboxEvents)
+	if err != nil {
+		h.logger.Errorf("failed to get dead outbox events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get dead outbox events"})
+		return
+	}
+
+	resp := make([]models.OutboxEventResponse, 0, len(events))
+	for _, e := range events {
+		resp = append(resp, models.OutboxEventResponse{
+			ID:             e.ID,
+			EventType:      e.EventType,
+			IdempotencyKey: e.IdempotencyKey,
+			Payload:        json.RawMessage(e.Payload),
+			Status:         e.Status,
+			Attempts:       e.Attempts,
+			LastError:      e.LastError,
+			CreatedAt:      e.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RetryOutboxEvent возвращает событие из dead-letter в очередь
+func (h *GameHandler) RetryOutboxEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	err = h.db.RetryOutboxEvent(c.Request.Context(), id)
+	if errors.Is(err, database.ErrOutboxEventNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead outbox event not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to retry outbox event %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retry outbox event"})
+		return
+	}
+
+	h.logger.Infof("outbox event %d requeued", id)
+	c.JSON(http.StatusOK, gin.H{"status": "requeued"})
+}
@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"game/models"
+	"game/sudoku"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	errSessionNotFound = errors.New("session not found")
+	errSessionClosed   = errors.New("session is already completed")
+	errSessionPaused   = errors.New("session is paused")
+	errSessionRunning  = errors.New("session is not paused")
+	errNothingToUndo   = errors.New("nothing to undo")
+	errGivenCell       = errors.New("cannot change a given cell")
+	errDailyNoPause    = errors.New("daily attempt cannot be paused")
+	errNoChecksLeft    = errors.New("no checks left in this session")
+)
+
+// solutionError — итоговое поле сессии не является решением
+type solutionError struct {
+	conflicts []sudoku.Conflict
+}
+
+func (e *solutionError) Error() string {
+	return "invalid solution"
+}
+
+func (h *GameHandler) StartSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.StartSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	field, err := h.db.GetSudokuForVerification(ctx, req.SudokuID)
+	if err != nil {
+		h.logger.Errorf("failed to get sudoku %s: %v", req.SudokuID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sudoku"})
+		return
+	}
+	if field == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sudoku not found"})
+		return
+	}
+
+	// Незавершённая сессия продолжается, в том числе с другого устройства
+	existing, err := h.db.GetOpenSession(ctx, userID, field.ID)
+	if err != nil {
+		h.logger.Errorf("failed to get open session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get session"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusOK, h.sessionResponse(existing, field))
+		return
+	}
+
+	now := time.Now().UTC()
+	session := &models.GameSession{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		FieldID:     field.ID,
+		Status:      models.SessionStatusActive,
+		CurrentGrid: field.InitialField,
+		PencilMarks: map[string]string{},
+		StartedAt:   now,
+		ResumedAt:   &now,
+	}
+
+	if err := h.db.CreateSession(ctx, session); err != nil {
+		h.logger.Errorf("failed to create session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	if err := h.db.MarkSudokuSeen(ctx, userID, field.ID); err != nil {
+		h.logger.Warnf("failed to mark sudoku %s seen by user %s: %v", field.ID, userID, err)
+	}
+
+	h.logger.Infof("game session %s started by user %s on sudoku %s", session.ID, userID, field.ID)
+	c.JSON(http.StatusCreated, h.sessionResponse(session, field))
+}
+
+func (h *GameHandler) GetMySessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	sessions, err := h.db.GetOpenSessions(ctx, userID)
+	if err != nil {
+		h.logger.Errorf("failed to get sessions for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sessions"})
+		return
+	}
+
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		field, err := h.db.GetSudokuForVerification(ctx, sessions[i].FieldID)
+		if err != nil || field == nil {
+			h.logger.Warnf("failed to get sudoku %s for session %s: %v", sessions[i].FieldID, sessions[i].ID, err)
+			continue
+		}
+		responses = append(responses, h.sessionResponse(&sessions[i], field))
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+func (h *GameHandler) GetSession(c *gin.Context) {
+	session, field, ok := h.loadOwnedSession(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.sessionResponse(session, field))
+}
+
+func (h *GameHandler) GetSessionMoves(c *gin.Context) {
+	session, _, ok := h.loadOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	moves, err := h.db.GetSessionMoves(c.Request.Context(), session.ID)
+	if err != nil {
+		h.logger.Errorf("failed to get moves for session %s: %v", session.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get moves"})
+		return
+	}
+
+	c.JSON(http.StatusOK, moves)
+}
+
+func (h *GameHandler) AddSessionMove(c *gin.Context) {
+	session, field, ok := h.loadOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	var req models.SessionMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	marks, err := normalizePencilMarks(req.PencilMarks)
+	if err != nil || req.Value < 0 || req.Value > sudoku.Size {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid move"})
+		return
+	}
+	// Номер клетки дальше служит индексом в строках поля
+	if req.Cell < 0 || req.Cell >= sudoku.CellCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid move"})
+		return
+	}
+	if given, _ := sudoku.CellValue(field.InitialField[req.Cell]); given != 0 {
+		h.respondSessionError(c, session.ID, errGivenCell)
+		return
+	}
+
+	ctx := c.Request.Context()
+	var move *models.GameMove
+
+	updated, err := h.updateSession(ctx, session.ID, func(tx *sqlx.Tx, s *models.GameSession) error {
+		if s.Status == models.SessionStatusPaused {
+			return errSessionPaused
+		}
+
+		key := strconv.Itoa(req.Cell)
+		prev, _ := sudoku.CellValue(s.CurrentGrid[req.Cell])
+		move = &models.GameMove{
+			SessionID:       s.ID,
+			Cell:            req.Cell,
+			Value:           req.Value,
+			PrevValue:       prev,
+			PencilMarks:     marks,
+			PrevPencilMarks: s.PencilMarks[key],
+		}
+		if err := h.db.AddMoveTx(ctx, tx, move); err != nil {
+			return err
+		}
+
+		s.CurrentGrid = setCell(s.CurrentGrid, req.Cell, req.Value)
+		setPencilMarks(s.PencilMarks, key, marks)
+		return nil
+	})
+	if err != nil {
+		h.respondSessionError(c, session.ID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"move":    move,
+		"session": h.sessionResponse(updated, field),
+	})
+}
+
+func (h *GameHandler) UndoSessionMove(c *gin.Context) {
+	session, field, ok := h.loadOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var undone *models.GameMove
+
+	updated, err := h.updateSession(ctx, session.ID, func(tx *sqlx.Tx, s *models.GameSession) error {
+		if s.Status == models.SessionStatusPaused {
+			return errSessionPaused
+		}
+
+		move, err := h.db.LastMoveTx(ctx, tx, s.ID)
+		if err != nil {
+			return err
+		}
+		if move == nil {
+			return errNothingToUndo
+		}
+		if err := h.db.MarkMoveUndoneTx(ctx, tx, move.ID); err != nil {
+			return err
+		}
+
+		s.CurrentGrid = setCell(s.CurrentGrid, move.Cell, move.PrevValue)
+		setPencilMarks(s.PencilMarks, strconv.Itoa(move.Cell), move.PrevPencilMarks)
+		undone = move
+		return nil
+	})
+	if err != nil {
+		h.respondSessionError(c, session.ID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"undone":  undone,
+		"session": h.sessionResponse(updated, field),
+	})
+}
+
+func (h *GameHandler) PauseSession(c *gin.Context) {
+	session, field, ok := h.loadOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	updated, err := h.updateSession(ctx, session.ID, func(tx *sqlx.Tx, s *models.GameSession) error {
+		if s.Status == models.SessionStatusPaused {
+			return errSessionPaused
+		}
+		// Время попытки дня идёт без остановки, иначе на паузе можно решать поле
+		daily, err := h.db.IsDailyAttemptTx(ctx, tx, s.ID)
+		if err != nil {
+			return err
+		}
+		if daily {
+			return errDailyNoPause
+		}
+		s.ElapsedMs = s.Elapsed(time.Now().UTC())
+		s.ResumedAt = nil
+		s.Status = models.SessionStatusPaused
+		return nil
+	})
+	if err != nil {
+		h.respondSessionError(c, session.ID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.sessionResponse(updated, field))
+}
+
+func (h *GameHandler) ResumeSession(c *gin.Context) {
+	session, field, ok := h.loadOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.updateSession(c.Request.Context(), session.ID, func(_ *sqlx.Tx, s *models.GameSession) error {
+		if s.Status != models.SessionStatusPaused {
+			return errSessionRunning
+		}
+		now := time.Now().UTC()
+		s.ResumedAt = &now
+		s.Status = models.SessionStatusActive
+		return nil
+	})
+	if err != nil {
+		h.respondSessionError(c, session.ID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.sessionResponse(updated, field))
+}
+
+// CompleteSession проверяет текущее поле сессии и засчитывает решение
+// с временем, посчитанным сервером
+func (h *GameHandler) CompleteSession(c *gin.Context) {
+	session, field, ok := h.loadOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	// Проверяется поле, прочитанное под блокировкой сессии: ход, сделанный
+	// параллельно с завершением, не попадёт в засчитанное решение непроверенным
+	completed, eventID, err := h.completeSession(c.Request.Context(), session.ID, "", field)
+	if err != nil {
+		h.respondSessionError(c, session.ID, err)
+		return
+	}
+
+	h.finishSolve(c, completed, field, eventID)
+}
+
+// completeSession проверяет итоговое поле (grid или, если оно пустое, текущее
+// поле сессии) и закрывает сессию, фиксируя поле и время. Приостановленную
+// сессию завершить нельзя. В той же транзакции засчитывается решение поля,
+// результат попытки дня и пишется событие outbox для статистики пользователя;
+// возвращается его id. Сессия вне зачёта (s.Ranked = false) в рекорды,
+// рейтинги и статистику не идёт, и id события равен 0.
+func (h *GameHandler) completeSession(ctx context.Context, sessionID, grid string, field *models.SudokuField) (*models.GameSession, int64, error) {
+	var event models.OutboxEvent
+
+	initial, err := sudoku.Parse(field.InitialField)
+	if err != nil {
+		return nil, 0, fmt.Errorf("stored sudoku %s has malformed initial field: %w", field.ID, err)
+	}
+
+	completed, err := h.updateSession(ctx, sessionID, func(tx *sqlx.Tx, s *models.GameSession) error {
+		if s.Status == models.SessionStatusPaused {
+			return errSessionPaused
+		}
+		if grid == "" {
+			grid = s.CurrentGrid
+		}
+		submitted, err := sudoku.Parse(grid)
+		if err != nil {
+			return fmt.Errorf("session %s has malformed grid: %w", s.ID, err)
+		}
+		if conflicts := sudoku.Verify(initial, submitted); len(conflicts) > 0 {
+			return &solutionError{conflicts: conflicts}
+		}
+
+		now := time.Now().UTC()
+		s.ElapsedMs = s.Elapsed(now)
+		s.ResumedAt = nil
+		s.CompletedAt = &now
+		s.CurrentGrid = submitted.String()
+		s.Status = models.SessionStatusCompleted
+
+		dailyRanked := s.Ranked && s.ElapsedMs >= minDailySolveTime.Milliseconds()
+		if err := h.db.CompleteDailyAttemptTx(ctx, tx, s.ID, s.ElapsedMs, now, dailyRanked); err != nil {
+			return err
+		}
+		if !s.Ranked {
+			return nil
+		}
+		if err := h.db.MarkSudokuSolvedTx(ctx, tx, field.ID, s.UserID, s.ElapsedMs); err != nil {
+			return err
+		}
+
+		payload, err := json.Marshal(models.UpdateStatsRequest{
+			UserID:      s.UserID,
+			Difficulty:  field.Complexity,
+			TimeSeconds: s.ElapsedMs / 1000,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal update stats: %w", err)
+		}
+		event = models.OutboxEvent{
+			EventType:      models.OutboxEventUserStats,
+			IdempotencyKey: "session-solved:" + s.ID,
+			Payload:        payload,
+		}
+		return h.db.AddOutboxEventTx(ctx, tx, &event)
+	})
+	return completed, event.ID, err
+}
+
+// updateSession блокирует открытую сессию, применяет fn и сохраняет результат
+func (h *GameHandler) updateSession(ctx context.Context, sessionID string, fn func(tx *sqlx.Tx, s *models.GameSession) error) (*models.GameSession, error) {
+	var updated *models.GameSession
+
+	err := h.db.WithTx(ctx, func(tx *sqlx.Tx) error {
+		s, err := h.db.LockSessionTx(ctx, tx, sessionID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return errSessionNotFound
+		}
+		if !s.IsOpen() {
+			return errSessionClosed
+		}
+
+		if err := fn(tx, s); err != nil {
+			return err
+		}
+		if err := h.db.SaveSessionTx(ctx, tx, s); err != nil {
+			return err
+		}
+		updated = s
+		return nil
+	})
+
+	return updated, err
+}
+
+func (h *GameHandler) loadOwnedSession(c *gin.Context) (*models.GameSession, *models.SudokuField, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, nil, false
+	}
+
+	ctx := c.Request.Context()
+	sessionID := c.Param("sid")
+
+	session, err := h.db.GetSession(ctx, sessionID)
+	if err != nil {
+		h.logger.Errorf("failed to get session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get session"})
+		return nil, nil, false
+	}
+	if session == nil || session.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return nil, nil, false
+	}
+
+	field, err := h.db.GetSudokuForVerification(ctx, session.FieldID)
+	if err != nil || field == nil {
+		h.logger.Errorf("failed to get sudoku %s for session %s: %v", session.FieldID, session.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sudoku"})
+		return nil, nil, false
+	}
+
+	return session, field, true
+}
+
+func (h *GameHandler) respondSessionError(c *gin.Context, sessionID string, err error) {
+	var solErr *solutionError
+	switch {
+	case errors.As(err, &solErr):
+		h.logger.Warnf("rejected solution in session %s: %d conflicts", sessionID, len(solErr.conflicts))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     "invalid solution",
+			"conflicts": solErr.conflicts,
+		})
+	case errors.Is(err, errSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errSessionClosed), errors.Is(err, errSessionPaused),
+		errors.Is(err, errSessionRunning), errors.Is(err, errNothingToUndo),
+		errors.Is(err, errDailyNoPause), errors.Is(err, errNoChecksLeft):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errGivenCell):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Errorf("failed to update session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update session"})
+	}
+}
+
+// sessionResponse не отдаёт поле приостановленной сессии: пока таймер стоит,
+// решать его нельзя
+func (h *GameHandler) sessionResponse(s *models.GameSession, field *models.SudokuField) models.SessionResponse {
+	resp := models.SessionResponse{
+		ID:           s.ID,
+		SudokuID:     s.FieldID,
+		Status:       s.Status,
+		InitialField: field.InitialField,
+		CurrentGrid:  s.CurrentGrid,
+		PencilMarks:  s.PencilMarks,
+		ElapsedMs:    s.Elapsed(time.Now().UTC()),
+		ChecksUsed:   s.ChecksUsed,
+		Ranked:       s.Ranked,
+		StartedAt:    s.StartedAt,
+		CompletedAt:  s.CompletedAt,
+	}
+	if s.Status == models.SessionStatusPaused {
+		resp.InitialField = ""
+		resp.CurrentGrid = ""
+		resp.PencilMarks = map[string]string{}
+	}
+	return resp
+}
+
+func setCell(grid string, cell, value int) string {
+	b := []byte(grid)
+	b[cell] = byte('0' + value)
+	return string(b)
+}
+
+func setPencilMarks(marks map[string]string, key, value string) {
+	if value == "" {
+		delete(marks, key)
+		return
+	}
+	marks[key] = value
+}
+
+// normalizePencilMarks оставляет уникальные цифры 1-9 по возрастанию
+func normalizePencilMarks(raw string) (string, error) {
+	var m sudoku.Mask
+	for _, ch := range strings.TrimSpace(raw) {
+		if ch < '1' || ch > '9' {
+			return "", errors.New("pencil marks must be digits 1-9")
+		}
+		m |= sudoku.Bit(int(ch - '0'))
+	}
+
+	var b strings.Builder
+	for _, d := range m.Digits() {
+		b.WriteByte(byte('0' + d))
+	}
+	return b.String(), nil
+}
@@ -1,17 +1,18 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"game/config"
 	"game/models"
+	"game/sudoku"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 )
 
+// GetSudokuByDifficulty подбирает головоломку. Само поле игрок получает только
+// при старте сессии (POST /sessions): с этого момента сервер считает время решения.
 func (h *GameHandler) GetSudokuByDifficulty(c *gin.Context) {
 	difficulty := c.Query("difficulty")
 	if difficulty == "" {
@@ -20,7 +21,8 @@ func (h *GameHandler) GetSudokuByDifficulty(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	field, err := h.db.GetRandomByComplexity(ctx, difficulty)
+	userID := c.GetString("user_id")
+	field, solved, err := h.db.PickSudoku(ctx, userID, difficulty, tagsQuery(c))
 	if err != nil {
 		h.logger.Errorf("failed to get sudoku: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
@@ -31,6 +33,19 @@ func (h *GameHandler) GetSudokuByDifficulty(c *gin.Context) {
 		return
 	}
 
+	if userID != "" {
+		if err := h.db.MarkSudokuSeen(ctx, userID, field.ID); err != nil {
+			h.logger.Warnf("failed to mark sudoku %s seen by user %s: %v", field.ID, userID, err)
+		}
+	}
+
+	tags, err := h.db.GetTagsForFields(ctx, []string{field.ID})
+	if err != nil {
+		h.logger.Errorf("failed to get sudoku tags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
 	var avgMs int64
 	if field.SolvesSuccessful > 0 {
 		avgMs = field.SolvesTotalTime / field.SolvesSuccessful
@@ -43,14 +58,17 @@ func (h *GameHandler) GetSudokuByDifficulty(c *gin.Context) {
 
 	resp := models.SudokuResponse{
 		ID:               field.ID,
-		InitialField:     field.InitialField,
-		Solution:         field.Solution,
 		Complexity:       field.Complexity,
 		CreatedAt:        field.CreatedAt.Format(time.RFC3339),
 		SolveAttempts:    field.SolveAttempts,
 		SolvesSuccessful: field.SolvesSuccessful,
 		AvgSolveTimeMs:   avgMs,
 		SuccessRate:      successRate,
+		Tags:             tags[field.ID],
+		Solved:           solved,
+	}
+	if isAdmin(c) {
+		resp.InitialField = field.InitialField
 	}
 
 	c.JSON(http.StatusOK, resp)
@@ -63,13 +81,34 @@ func (h *GameHandler) GetAllSudokuByDifficulty(c *gin.Context) {
 		return
 	}
 
-	fields, err := h.db.GetFieldsByComplexity(c.Request.Context(), difficulty)
+	ctx := c.Request.Context()
+	fields, err := h.db.GetFieldsByComplexity(ctx, difficulty, tagsQuery(c))
 	if err != nil {
 		h.logger.Errorf("failed to get sudokus: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sudokus by difficulty"})
 		return
 	}
 
+	ids := make([]string, 0, len(fields))
+	for _, f := range fields {
+		ids = append(ids, f.ID)
+	}
+	tags, err := h.db.GetTagsForFields(ctx, ids)
+	if err != nil {
+		h.logger.Errorf("failed to get sudoku tags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sudokus by difficulty"})
+		return
+	}
+	solved, err := h.db.GetSolvedFieldIDs(ctx, c.GetString("user_id"), ids)
+	if err != nil {
+		h.logger.Errorf("failed to get solved sudokus: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sudokus by difficulty"})
+		return
+	}
+
+	// Поле и решение видит только администратор
+	withSolution := isAdmin(c)
+
 	responses := make([]models.SudokuResponse, 0, len(fields))
 	for _, f := range fields {
 		avg := int64(0)
@@ -84,20 +123,25 @@ func (h *GameHandler) GetAllSudokuByDifficulty(c *gin.Context) {
 
 		responses = append(responses, models.SudokuResponse{
 			ID:               f.ID,
-			InitialField:     f.InitialField,
-			Solution:         f.Solution,
 			Complexity:       f.Complexity,
 			CreatedAt:        f.CreatedAt.Format(time.RFC3339),
 			SolveAttempts:    f.SolveAttempts,
 			SolvesSuccessful: f.SolvesSuccessful,
 			AvgSolveTimeMs:   avg,
 			SuccessRate:      rate,
+			Tags:             tags[f.ID],
+			Solved:           solved[f.ID],
 		})
+		if withSolution {
+			responses[len(responses)-1].InitialField = f.InitialField
+			responses[len(responses)-1].Solution = f.Solution
+		}
 	}
 
 	c.JSON(http.StatusOK, responses)
 }
 
+// GetSudokuByID отдаёт сведения о головоломке; поле, как и в списках, видит только администратор
 func (h *GameHandler) GetSudokuByID(c *gin.Context) {
 	id := c.Param("id")
 
@@ -113,6 +157,33 @@ func (h *GameHandler) GetSudokuByID(c *gin.Context) {
 		return
 	}
 
+	// Головоломка дня доступна только через попытку дня
+	if !isAdmin(c) {
+		daily, err := h.db.IsDailyPuzzle(ctx, dailyDate(time.Now()), field.ID)
+		if err != nil {
+			h.logger.WithField("sudoku_id", id).Errorf("failed to check daily puzzle: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		if daily {
+			c.JSON(http.StatusForbidden, gin.H{"error": "daily puzzle is available through /daily"})
+			return
+		}
+	}
+
+	tags, err := h.db.GetTagsForFields(ctx, []string{field.ID})
+	if err != nil {
+		h.logger.WithField("sudoku_id", id).Errorf("failed to get sudoku tags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	solved, err := h.db.GetSolvedFieldIDs(ctx, c.GetString("user_id"), []string{field.ID})
+	if err != nil {
+		h.logger.WithField("sudoku_id", id).Errorf("failed to get solve history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
 	var avgSolveTimeMs int64
 	if field.SolvesSuccessful > 0 {
 		avgSolveTimeMs = field.SolvesTotalTime / field.SolvesSuccessful
@@ -125,14 +196,17 @@ func (h *GameHandler) GetSudokuByID(c *gin.Context) {
 
 	resp := models.SudokuResponse{
 		ID:               field.ID,
-		InitialField:     field.InitialField,
-		Solution:         field.Solution,
 		Complexity:       field.Complexity,
 		CreatedAt:        field.CreatedAt.Format(time.RFC3339),
 		SolveAttempts:    field.SolveAttempts,
 		SolvesSuccessful: field.SolvesSuccessful,
 		AvgSolveTimeMs:   avgSolveTimeMs,
 		SuccessRate:      successRate,
+		Tags:             tags[field.ID],
+		Solved:           solved[field.ID],
+	}
+	if isAdmin(c) {
+		resp.InitialField = field.InitialField
 	}
 
 	h.logger.WithField("sudoku_id", field.ID).Info("sudoku fetched")
@@ -141,7 +215,6 @@ func (h *GameHandler) GetSudokuByID(c *gin.Context) {
 
 func (h *GameHandler) ReportSolved(c *gin.Context) {
 	id := c.Param("id")
-	cfg := c.MustGet("config").(*config.Config)
 
 	if id == "" {
 		h.logger.Warn("missing sudoku id in path")
@@ -156,138 +229,252 @@ func (h *GameHandler) ReportSolved(c *gin.Context) {
 		return
 	}
 
-	if req.SolveTimeMs <= 0 {
-		h.logger.Warn("solve_time_ms must be > 0")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "solve_time_ms must be > 0"})
+	userID, exists := c.Get("user_id")
+	if !exists || userID != req.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to report solves for this user"})
+		return
+	}
+
+	submitted, err := sudoku.Parse(req.Grid)
+	if err != nil {
+		h.logger.Warnf("invalid grid in solve report for sudoku %s: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid grid: " + err.Error()})
 		return
 	}
 
 	ctx := c.Request.Context()
 
-	difficulty, err := h.db.GetSudokuDifficultyByID(ctx, id)
+	field, err := h.db.GetSudokuForVerification(ctx, id)
 	if err != nil {
-		h.logger.Errorf("failed to get difficulty for sudoku %s: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get difficulty"})
+		h.logger.Errorf("failed to get sudoku %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sudoku"})
 		return
 	}
-
-	// 1. Отмечаем, что судоку решено
-	if err := h.db.MarkSudokuSolved(ctx, id, req.SolveTimeMs); err != nil {
-		h.logger.Errorf("failed to mark sudoku as solved: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark sudoku as solved"})
+	if field == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
 
-	// 2. Отправляем обновление статистики
-	updateReq := models.UpdateStatsRequest{
-		UserID:      req.UserID,
-		Difficulty:  difficulty,
-		TimeSeconds: req.SolveTimeMs / 1000,
-	}
-	body, err := json.Marshal(updateReq)
+	// Время решения считает сервер по игровой сессии
+	session, err := h.db.GetOpenSession(ctx, req.UserID, id)
 	if err != nil {
-		h.logger.Errorf("failed to marshal update stats: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to marshal update request"})
+		h.logger.Errorf("failed to get game session for sudoku %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get game session"})
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "no active game session for this sudoku"})
 		return
 	}
 
-	patchURL := fmt.Sprintf("%s/%s/statistics", cfg.UsersURL, req.UserID)
-	patchReq, err := http.NewRequest(http.MethodPatch, patchURL, bytes.NewBuffer(body))
+	// Присланное решение проверяется на сервере в completeSession
+	completed, eventID, err := h.completeSession(ctx, session.ID, submitted.String(), field)
 	if err != nil {
-		h.logger.Errorf("failed to create PATCH request: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create update request"})
+		h.respondSessionError(c, session.ID, err)
 		return
 	}
-	patchReq.Header.Set("Content-Type", "application/json")
 
-	patchResp, err := http.DefaultClient.Do(patchReq)
-	if err != nil {
-		h.logger.Errorf("failed to send update stats request: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to contact users service"})
+	h.finishSolve(c, completed, field, eventID)
+}
+
+// finishSolve досылает статистику пользователя и выдаёт достижения.
+// Само решение уже засчитано в completeSession; если сервис пользователей
+// недоступен, событие статистики остаётся в outbox и будет доставлено позже.
+func (h *GameHandler) finishSolve(c *gin.Context, session *models.GameSession, field *models.SudokuField, statsEventID int64) {
+	ctx := c.Request.Context()
+	id := field.ID
+	userID := session.UserID
+	solveTimeMs := session.ElapsedMs
+
+	// Поле пользователь уже видел в прошлой сессии: время не показательно
+	if !session.Ranked {
+		h.logger.Infof("sudoku %s solved by user %s outside of ranking", id, userID)
+		c.JSON(http.StatusOK, gin.H{
+			"status":            "solved, not ranked",
+			"ranked":            false,
+			"solve_time_ms":     solveTimeMs,
+			"qualified_rewards": []models.Achievement{},
+		})
 		return
 	}
-	defer patchResp.Body.Close()
 
-	if patchResp.StatusCode != http.StatusOK {
-		var errResp models.ErrorResponse
-		_ = json.NewDecoder(patchResp.Body).Decode(&errResp)
-		h.logger.Warnf("users service update failed: %v", errResp.Error)
-		c.JSON(patchResp.StatusCode, gin.H{"error": "users service update failed"})
+	// 2. Отправляем обновление статистики (шаг 1 — в completeSession)
+	if err := h.outbox.DispatchNow(ctx, statsEventID); err != nil {
+		h.logger.Warnf("stats update for user %s queued after failed delivery: %v", userID, err)
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":            "solved, stats update queued",
+			"solve_time_ms":     solveTimeMs,
+			"qualified_rewards": []models.Achievement{},
+		})
 		return
 	}
 
-	// 3. Получаем обновлённую статистику
-	statsURL := fmt.Sprintf("%s/%s/statistics", cfg.UsersURL, req.UserID)
-	statsResp, err := http.Get(statsURL)
+	// 3. Проверяем и выдаём достижения по обновлённой статистике
+	result, err := h.issuer.CheckUser(ctx, userID)
 	if err != nil {
-		h.logger.Errorf("failed to fetch updated stats: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch updated statistics"})
+		h.logger.Errorf("failed to check achievements for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check achievements"})
 		return
 	}
-	defer statsResp.Body.Close()
 
-	if statsResp.StatusCode != http.StatusOK {
-		h.logger.Warnf("users service GET stats failed: status %d", statsResp.StatusCode)
-		c.JSON(statsResp.StatusCode, gin.H{"error": "failed to fetch statistics"})
-		return
+	hintsUsed, err := h.db.CountHints(ctx, session.ID)
+	if err != nil {
+		h.logger.Warnf("failed to count hints in session %s: %v", session.ID, err)
 	}
 
-	var stats models.UserStatisticsResponse
+	h.logger.Infof("sudoku %s solved by user %s with %d hints, stats updated and fetched", id, userID, hintsUsed)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":            "solved and stats updated",
+		"stats":             result.Stats,
+		"hints_used":        hintsUsed,
+		"solve_time_ms":     solveTimeMs,
+		"qualified_rewards": result.Awarded,
+	})
+}
 
-	// 4. Получаем все достижения из базы
-	achievements, err := h.db.GetAllAchievements(ctx)
+func (h *GameHandler) loadSolution(c *gin.Context, id string) (sudoku.Grid, bool) {
+	field, err := h.db.GetSudokuForVerification(c.Request.Context(), id)
 	if err != nil {
-		h.logger.Errorf("failed to load achievements: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load achievements"})
+		h.logger.WithField("sudoku_id", id).Errorf("failed to get sudoku: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return sudoku.Grid{}, false
+	}
+	if field == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return sudoku.Grid{}, false
+	}
+
+	solution, err := sudoku.Parse(field.Solution)
+	if err != nil {
+		h.logger.WithField("sudoku_id", id).Errorf("stored solution is malformed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "malformed sudoku"})
+		return sudoku.Grid{}, false
+	}
+	return solution, true
+}
+
+// maxSessionChecks — сколько раз за попытку можно проверить поле
+const maxSessionChecks = 3
+
+// CheckSudokuCells отмечает неверные цифры в открытой сессии игрока.
+// Проверяется поле сессии, а не присланное клиентом, и число проверок
+// ограничено: иначе перебором цифр решение открывалось бы за несколько запросов.
+func (h *GameHandler) CheckSudokuCells(c *gin.Context) {
+	id := c.Param("id")
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	if err := json.NewDecoder(statsResp.Body).Decode(&stats); err != nil {
-		h.logger.Errorf("failed to decode user statistics: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid statistics response"})
+	solution, ok := h.loadSolution(c, id)
+	if !ok {
+		return
+	}
+	session, ok := h.loadOpenSession(c, userID, id)
+	if !ok {
 		return
 	}
 
-	// 5. Проверяем какие достижения выполнены
-	qualified := EvaluateAchievements(stats, achievements)
+	var resp models.SudokuCheckResponse
+	_, err := h.updateSession(c.Request.Context(), session.ID, func(_ *sqlx.Tx, s *models.GameSession) error {
+		if s.Status == models.SessionStatusPaused {
+			return errSessionPaused
+		}
+		if s.ChecksUsed >= maxSessionChecks {
+			return errNoChecksLeft
+		}
+		current, err := sudoku.Parse(s.CurrentGrid)
+		if err != nil {
+			return fmt.Errorf("session %s has malformed grid: %w", s.ID, err)
+		}
 
-	// 6. Получаем уже выданные пользователю достижения
-	existingCodes, err := h.db.GetUserAchievements(ctx, req.UserID)
+		// Данные цифры всегда верны, поэтому в ответ попадают только клетки,
+		// заполненные игроком в этой сессии
+		resp.WrongCells = sudoku.WrongCells(solution, current)
+		resp.Complete = len(resp.WrongCells) == 0 && current.IsComplete()
+		s.ChecksUsed++
+		resp.ChecksLeft = maxSessionChecks - s.ChecksUsed
+		return nil
+	})
 	if err != nil {
-		h.logger.Errorf("failed to fetch user achievements: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch user achievements"})
+		h.respondSessionError(c, session.ID, err)
 		return
 	}
-	existingSet := make(map[string]struct{}, len(existingCodes))
-	for _, ach := range existingCodes {
-		existingSet[ach.Code] = struct{}{}
+
+	if resp.WrongCells == nil {
+		resp.WrongCells = []int{}
 	}
+	c.JSON(http.StatusOK, resp)
+}
 
-	// 7. Отфильтровываем уже выданные
-	var newAchievements []models.Achievement
-	for _, ach := range qualified {
-		if _, exists := existingSet[ach.Code]; !exists {
-			newAchievements = append(newAchievements, ach)
-		}
+// loadOpenSession возвращает открытую сессию пользователя на поле; без неё
+// проверки и подсказки недоступны. При ошибке ответ уже отправлен.
+func (h *GameHandler) loadOpenSession(c *gin.Context, userID, fieldID string) (*models.GameSession, bool) {
+	session, err := h.db.GetOpenSession(c.Request.Context(), userID, fieldID)
+	if err != nil {
+		h.logger.Errorf("failed to get game session for sudoku %s: %v", fieldID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get game session"})
+		return nil, false
+	}
+	if session == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "no active game session for this sudoku"})
+		return nil, false
+	}
+	return session, true
+}
+
+// GetSudokuHint открывает одну клетку поля открытой сессии; подсказка засчитывается в попытку
+func (h *GameHandler) GetSudokuHint(c *gin.Context) {
+	id := c.Param("id")
+
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	solution, ok := h.loadSolution(c, id)
+	if !ok {
+		return
+	}
+	session, ok := h.loadOpenSession(c, userID, id)
+	if !ok {
+		return
+	}
+	if session.Status == models.SessionStatusPaused {
+		h.respondSessionError(c, session.ID, errSessionPaused)
+		return
 	}
 
-	// 8. Сохраняем новые достижения
-	var codes []string
-	for _, a := range newAchievements {
-		codes = append(codes, a.Code)
+	current, err := sudoku.Parse(session.CurrentGrid)
+	if err != nil {
+		h.logger.Errorf("session %s has malformed grid: %v", session.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "malformed session"})
+		return
 	}
 
-	if err := h.db.AssignAchievements(ctx, req.UserID, codes); err != nil {
-		h.logger.Errorf("failed to assign achievements: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign achievements"})
+	cell := sudoku.PickHint(solution, current)
+	if cell < 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "nothing to hint, the grid is already solved"})
 		return
 	}
 
-	h.logger.Infof("sudoku %s solved by user %s, stats updated and fetched", id, req.UserID)
+	// Подсказка засчитывается в текущую попытку пользователя
+	used, err := h.db.AddHint(c.Request.Context(), session.ID, cell)
+	if err != nil {
+		h.logger.Errorf("failed to record hint in session %s: %v", session.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record hint"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":            "solved and stats updated",
-		"stats":             stats,
-		"qualified_rewards": newAchievements,
+	c.JSON(http.StatusOK, models.SudokuHintResponse{
+		Cell:      cell,
+		Row:       sudoku.RowOf(cell),
+		Col:       sudoku.ColOf(cell),
+		Value:     solution[cell],
+		HintsUsed: used,
 	})
 }
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"game/database"
+	"game/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *GameHandler) GetAllTags(c *gin.Context) {
+	tags, err := h.db.GetAllTags(c.Request.Context())
+	if err != nil {
+		h.logger.Errorf("failed to get tags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get tags"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+func (h *GameHandler) CreateTag(c *gin.Context) {
+	var req models.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	name := normalizeTag(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	tag, err := h.db.CreateTag(c.Request.Context(), name)
+	if err != nil {
+		h.logger.Errorf("failed to create tag %s: %v", name, err)
+		c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+		return
+	}
+	c.JSON(http.StatusCreated, tag)
+}
+
+func (h *GameHandler) RenameTag(c *gin.Context) {
+	var req models.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	name := normalizeTag(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	err := h.db.RenameTag(c.Request.Context(), c.Param("name"), name)
+	if errors.Is(err, database.ErrTagNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to rename tag %s: %v", c.Param("name"), err)
+		c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"name": name})
+}
+
+func (h *GameHandler) DeleteTag(c *gin.Context) {
+	err := h.db.DeleteTag(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, database.ErrTagNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to delete tag %s: %v", c.Param("name"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete tag"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TagSudoku привязывает к головоломке существующие теги
+func (h *GameHandler) TagSudoku(c *gin.Context) {
+	var req models.TagSudokuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	names := make([]string, 0, len(req.Tags))
+	for _, t := range req.Tags {
+		if t = normalizeTag(t); t != "" {
+			names = append(names, t)
+		}
+	}
+	if len(names) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	field, err := h.db.GetSudokuForVerification(ctx, id)
+	if err != nil {
+		h.logger.WithField("sudoku_id", id).Errorf("failed to get sudoku: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if field == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sudoku not found"})
+		return
+	}
+
+	err = h.db.TagField(ctx, id, names)
+	if errors.Is(err, database.ErrTagNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	if err != nil {
+		h.logger.WithField("sudoku_id", id).Errorf("failed to tag sudoku: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to tag sudoku"})
+		return
+	}
+
+	tags, err := h.db.GetTagsForFields(ctx, []string{id})
+	if err != nil {
+		h.logger.WithField("sudoku_id", id).Errorf("failed to get sudoku tags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "tags": tags[id]})
+}
+
+func (h *GameHandler) UntagSudoku(c *gin.Context) {
+	id := c.Param("id")
+	err := h.db.UntagField(c.Request.Context(), id, c.Param("name"))
+	if errors.Is(err, database.ErrTagNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+	if err != nil {
+		h.logger.WithField("sudoku_id", id).Errorf("failed to untag sudoku: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to untag sudoku"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func normalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// tagsQuery читает фильтр ?tags=a,b (или несколько ?tags=)
+func tagsQuery(c *gin.Context) []string {
+	var tags []string
+	for _, v := range c.QueryArray("tags") {
+		for _, t := range strings.Split(v, ",") {
+			if t = normalizeTag(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+	}
+	return tags
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"game/models"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,7 +15,7 @@ func (h *GameHandler) GetUserAchievements(c *gin.Context) {
 		return
 	}
 
-	achievements, err := h.db.GetUserAchievements(c.Request.Context(), userID)
+	achievements, err := h.db.GetUserAchievementsWithRarity(c.Request.Context(), userID)
 	if err != nil {
 		h.logger.Errorf("failed to fetch achievements for user %s: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch achievements"})
@@ -80,3 +81,36 @@ func (h *GameHandler) DeleteUserAchievement(c *gin.Context) {
 	h.logger.Infof("achievement %s removed from user %s", code, userID)
 	c.Status(http.StatusNoContent)
 }
+
+// GetAchievementScore возвращает очки пользователя за достижения и его место
+func (h *GameHandler) GetAchievementScore(c *gin.Context) {
+	userID := c.Param("id")
+
+	score, err := h.db.GetAchievementScore(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("failed to get achievement score for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get achievement score"})
+		return
+	}
+	c.JSON(http.StatusOK, score)
+}
+
+func (h *GameHandler) GetAchievementLeaderboard(c *gin.Context) {
+	limit := defaultLeaderboardLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	scores, err := h.db.GetAchievementLeaderboard(c.Request.Context(), limit)
+	if err != nil {
+		h.logger.Errorf("failed to get achievement leaderboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get leaderboard"})
+		return
+	}
+	c.JSON(http.StatusOK, scores)
+}
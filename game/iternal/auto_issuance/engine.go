@@ -0,0 +1,296 @@
+package auto_issuance
+
+import (
+	"fmt"
+	"game/models"
+	"slices"
+)
+
+// Rule вычисляет прогресс пользователя по условию одного типа;
+// условие выполнено, когда Progress.Achieved
+type Rule func(f *Facts, cond models.AchievementCondition) Progress
+
+// Engine вычисляет условия достижений, включая составные
+type Engine struct {
+	rules map[string]Rule
+}
+
+func NewEngine() *Engine {
+	e := &Engine{rules: make(map[string]Rule)}
+	e.Register("solved_count", solvedCount)
+	e.Register("best_time", bestTime)
+	e.Register("multi_difficulty_solved", multiDifficultySolved)
+	e.Register("streak_days", streakDays)
+	e.Register("total_solved", totalSolved)
+	e.Register("total_time", totalTime)
+	e.Register("no_hint_solves", noHintSolves)
+	e.Register("tournament_rank", tournamentRank)
+	e.Register("tournaments_joined", tournamentsJoined)
+	return e
+}
+
+func (e *Engine) Register(conditionType string, rule Rule) {
+	e.rules[conditionType] = rule
+}
+
+// Validate проверяет, что условие можно вычислить: типы известны,
+// а у составных условий есть вложенные
+func (e *Engine) Validate(cond models.AchievementCondition) error {
+	switch cond.Type {
+	case ConditionAll, ConditionAny:
+		if len(cond.Conditions) == 0 {
+			return fmt.Errorf("%q condition requires non-empty conditions", cond.Type)
+		}
+		for _, c := range cond.Conditions {
+			if err := e.Validate(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ConditionNot:
+		if cond.Condition == nil {
+			return fmt.Errorf("%q condition requires condition", cond.Type)
+		}
+		return e.Validate(*cond.Condition)
+	}
+
+	if _, ok := e.rules[cond.Type]; !ok {
+		return fmt.Errorf("unknown condition type %q", cond.Type)
+	}
+	return nil
+}
+
+// ValidateSeries проверяет уровни серии: одно и то же простое условие,
+// порог которого строго растёт от bronze к gold
+func (e *Engine) ValidateSeries(tiers []models.Achievement) error {
+	sorted := slices.Clone(tiers)
+	slices.SortFunc(sorted, func(a, b models.Achievement) int {
+		return models.TierIndex(a.Tier) - models.TierIndex(b.Tier)
+	})
+
+	for i, a := range sorted {
+		if models.TierIndex(a.Tier) < 0 {
+			return fmt.Errorf("unknown tier %q", a.Tier)
+		}
+		if _, _, ok := threshold(a.Condition); !ok {
+			return fmt.Errorf("condition %q cannot be tiered", a.Condition.Type)
+		}
+		if i == 0 {
+			continue
+		}
+
+		prev := sorted[i-1]
+		if a.Tier == prev.Tier {
+			return fmt.Errorf("tier %q is already taken in series", a.Tier)
+		}
+		if !sameCondition(prev.Condition, a.Condition) {
+			return fmt.Errorf("tiers of a series must share the same condition")
+		}
+		lo, lowerIsHarder, _ := threshold(prev.Condition)
+		hi, _, _ := threshold(a.Condition)
+		if lowerIsHarder && hi >= lo || !lowerIsHarder && hi <= lo {
+			return fmt.Errorf("%s threshold must be harder than %s", a.Tier, prev.Tier)
+		}
+	}
+	return nil
+}
+
+// threshold возвращает порог простого условия; для времени и места
+// меньшее значение сложнее
+func threshold(cond models.AchievementCondition) (value int, lowerIsHarder, ok bool) {
+	switch cond.Type {
+	case "solved_count", "streak_days", "total_solved", "no_hint_solves", "tournaments_joined":
+		return cond.Count, false, true
+	case "total_time":
+		return cond.Seconds, false, true
+	case "best_time":
+		return cond.MaxSeconds, true, true
+	case "tournament_rank":
+		return cond.Rank, true, true
+	}
+	return 0, false, false
+}
+
+func sameCondition(a, b models.AchievementCondition) bool {
+	return a.Type == b.Type && a.Difficulty == b.Difficulty
+}
+
+// Evaluate вычисляет условие; ошибка — условие невалидно
+func (e *Engine) Evaluate(cond models.AchievementCondition, f *Facts) (bool, error) {
+	p, err := e.Progress(cond, f)
+	return p.Achieved, err
+}
+
+// Progress вычисляет прогресс по условию; ошибка — условие невалидно
+func (e *Engine) Progress(cond models.AchievementCondition, f *Facts) (Progress, error) {
+	if err := e.Validate(cond); err != nil {
+		return Progress{Type: cond.Type}, err
+	}
+	return e.progress(cond, f), nil
+}
+
+func (e *Engine) progress(cond models.AchievementCondition, f *Facts) Progress {
+	switch cond.Type {
+	case ConditionAll, ConditionAny:
+		parts := make([]Progress, 0, len(cond.Conditions))
+		for _, c := range cond.Conditions {
+			parts = append(parts, e.progress(c, f))
+		}
+		return composite(cond.Type, parts)
+	case ConditionNot:
+		inner := e.progress(*cond.Condition, f)
+		p := atLeast(0, 1)
+		if !inner.Achieved {
+			p = atLeast(1, 1)
+		}
+		p.Parts = []Progress{inner}
+		p.Type = cond.Type
+		return p
+	}
+
+	p := e.rules[cond.Type](f, cond)
+	p.Type = cond.Type
+	return p
+}
+
+// composite: для "all" прогресс — среднее по частям, для "any" — лучшая часть
+func composite(kind string, parts []Progress) Progress {
+	achieved := 0
+	sum, best := 0.0, 0.0
+	for _, p := range parts {
+		if p.Achieved {
+			achieved++
+		}
+		sum += p.Ratio
+		if p.Ratio > best {
+			best = p.Ratio
+		}
+	}
+
+	var p Progress
+	if kind == ConditionAll {
+		p = atLeast(achieved, len(parts))
+		p.Ratio = sum / float64(len(parts))
+	} else {
+		p = atLeast(min(achieved, 1), 1)
+		p.Ratio = best
+	}
+	if p.Achieved {
+		p.Ratio = 1
+	}
+	p.Type = kind
+	p.Parts = parts
+	return p
+}
+
+// atLeast — прогресс к цели «набрать не меньше target»
+func atLeast(current, target int) Progress {
+	p := Progress{Current: current, Target: target, Achieved: current >= target}
+	switch {
+	case p.Achieved:
+		p.Ratio = 1
+	case target > 0:
+		p.Ratio = float64(current) / float64(target)
+	}
+	return p
+}
+
+// atMost — прогресс к цели «не больше target» (время, место); nil — результата ещё нет
+func atMost(current *int, target int) Progress {
+	p := Progress{Target: target, LowerIsBetter: true}
+	if current == nil {
+		return p
+	}
+	p.Current = *current
+	p.Achieved = *current <= target
+	switch {
+	case p.Achieved:
+		p.Ratio = 1
+	case *current > 0:
+		p.Ratio = float64(target) / float64(*current)
+	}
+	return p
+}
+
+func statFor(f *Facts, difficulty string) (models.DifficultyStatEntry, bool) {
+	for _, s := range f.Stats.Statistics {
+		if s.Difficulty == difficulty {
+			return s, true
+		}
+	}
+	return models.DifficultyStatEntry{}, false
+}
+
+func solvedCount(f *Facts, cond models.AchievementCondition) Progress {
+	s, _ := statFor(f, cond.Difficulty)
+	return atLeast(s.TotalSolved, cond.Count)
+}
+
+func bestTime(f *Facts, cond models.AchievementCondition) Progress {
+	s, _ := statFor(f, cond.Difficulty)
+	return atMost(s.BestTimeSeconds, cond.MaxSeconds)
+}
+
+func multiDifficultySolved(f *Facts, cond models.AchievementCondition) Progress {
+	count := 0
+	for _, level := range cond.Levels {
+		if s, ok := statFor(f, level); ok && s.TotalSolved > 0 {
+			count++
+		}
+	}
+	return atLeast(count, len(cond.Levels))
+}
+
+func streakDays(f *Facts, cond models.AchievementCondition) Progress {
+	return atLeast(f.LongestStreakDays, cond.Count)
+}
+
+func totalSolved(f *Facts, cond models.AchievementCondition) Progress {
+	total := 0
+	for _, s := range f.Stats.Statistics {
+		total += s.TotalSolved
+	}
+	return atLeast(total, cond.Count)
+}
+
+func totalTime(f *Facts, cond models.AchievementCondition) Progress {
+	total := 0
+	for _, s := range f.Stats.Statistics {
+		total += s.TotalTimeSeconds
+	}
+	return atLeast(total, cond.Seconds)
+}
+
+// noHintSolves — решения без подсказок на сложности cond.Difficulty или на любой, если она не задана
+func noHintSolves(f *Facts, cond models.AchievementCondition) Progress {
+	if cond.Difficulty != "" {
+		return atLeast(f.NoHintSolves[cond.Difficulty], cond.Count)
+	}
+	total := 0
+	for _, n := range f.NoHintSolves {
+		total += n
+	}
+	return atLeast(total, cond.Count)
+}
+
+// tournamentRank — хотя бы раз занять место не ниже cond.Rank
+func tournamentRank(f *Facts, cond models.AchievementCondition) Progress {
+	if f.Tournaments == nil {
+		return atMost(nil, cond.Rank)
+	}
+	var best *int
+	for _, r := range f.Tournaments.Results {
+		if best == nil || r.Rank < *best {
+			rank := r.Rank
+			best = &rank
+		}
+	}
+	return atMost(best, cond.Rank)
+}
+
+func tournamentsJoined(f *Facts, cond models.AchievementCondition) Progress {
+	if f.Tournaments == nil {
+		return atLeast(0, cond.Count)
+	}
+	return atLeast(f.Tournaments.TournamentsJoined, cond.Count)
+}
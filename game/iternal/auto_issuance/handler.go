@@ -1 +1,78 @@
 package auto_issuance
+
+import (
+	"database/sql"
+	"errors"
+	"game/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type Handler struct {
+	issuer *Issuer
+	logger *logrus.Logger
+}
+
+func NewHandler(issuer *Issuer, logger *logrus.Logger) *Handler {
+	return &Handler{issuer: issuer, logger: logger}
+}
+
+// CheckAndAssignAchievements проверяет и выдаёт достижения пользователю :id.
+// Запустить проверку может сам пользователь или администратор.
+func (h *Handler) CheckAndAssignAchievements(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing user id"})
+		return
+	}
+	if c.GetString("user_id") != userID && c.GetString("user_role") != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	result, err := h.issuer.CheckUser(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("failed to check achievements for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check achievements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"qualified_rewards": result.Awarded})
+}
+
+// GetAchievementProgress отдаёт прогресс пользователя :id по всем достижениям
+func (h *Handler) GetAchievementProgress(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing user id"})
+		return
+	}
+
+	report, err := h.issuer.Progress(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Errorf("failed to compute achievement progress for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute achievement progress"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ReevaluateAchievement пересчитывает достижение :code для всех пользователей в фоне
+func (h *Handler) ReevaluateAchievement(c *gin.Context) {
+	code := c.Param("code")
+	if _, err := h.issuer.repo.Achievement(code); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "achievement not found"})
+			return
+		}
+		h.logger.Errorf("failed to get achievement %s: %v", code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get achievement"})
+		return
+	}
+
+	h.issuer.ReevaluateAsync(code)
+	c.JSON(http.StatusAccepted, gin.H{"status": "reevaluation started"})
+}
@@ -0,0 +1,206 @@
+package auto_issuance
+
+import (
+	"context"
+	"encoding/json"
+	"game/models"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+const nextUpSize = 3
+
+// Issuer проверяет условия достижений и выдаёт выполненные
+type Issuer struct {
+	engine *Engine
+	repo   *Repository
+	logger *logrus.Logger
+}
+
+func NewIssuer(engine *Engine, repo *Repository, logger *logrus.Logger) *Issuer {
+	return &Issuer{engine: engine, repo: repo, logger: logger}
+}
+
+func (i *Issuer) loadFacts(ctx context.Context, userID string) (*Facts, error) {
+	facts, err := i.repo.LoadFacts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	facts.Tournaments, err = i.repo.LoadTournaments(ctx, userID)
+	if err != nil {
+		i.logger.Warnf("tournament facts unavailable for user %s: %v", userID, err)
+	}
+	return facts, nil
+}
+
+func (i *Issuer) ValidateCondition(cond models.AchievementCondition) error {
+	return i.engine.Validate(cond)
+}
+
+func (i *Issuer) ValidateSeries(tiers []models.Achievement) error {
+	return i.engine.ValidateSeries(tiers)
+}
+
+// CheckUser выдаёт пользователю все достижения, условия которых выполнены
+func (i *Issuer) CheckUser(ctx context.Context, userID string) (*Result, error) {
+	facts, err := i.loadFacts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	achievements, err := i.repo.Achievements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := i.repo.UserAchievementCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Stats: facts.Stats, Awarded: []models.Achievement{}}
+	var codes []string
+	for _, ach := range achievements {
+		if owned[ach.Code] {
+			continue
+		}
+		ok, err := i.engine.Evaluate(ach.Condition, facts)
+		if err != nil {
+			i.logger.Warnf("achievement %s has invalid condition: %v", ach.Code, err)
+			continue
+		}
+		if ok {
+			result.Awarded = append(result.Awarded, ach)
+			codes = append(codes, ach.Code)
+		}
+	}
+
+	if err := i.repo.Assign(ctx, userID, codes); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Progress считает прогресс пользователя по всем достижениям.
+// В next_up попадают nextUpSize неполученных достижений с наибольшим прогрессом.
+func (i *Issuer) Progress(ctx context.Context, userID string) (*ProgressReport, error) {
+	facts, err := i.loadFacts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	achievements, err := i.repo.Achievements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := i.repo.UserAchievementCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ProgressReport{
+		UserID:       userID,
+		Achievements: make([]AchievementProgress, 0, len(achievements)),
+		NextUp:       []AchievementProgress{},
+	}
+	for _, ach := range achievements {
+		p, err := i.engine.Progress(ach.Condition, facts)
+		if err != nil {
+			i.logger.Warnf("achievement %s has invalid condition: %v", ach.Code, err)
+			continue
+		}
+		report.Achievements = append(report.Achievements, AchievementProgress{
+			Code:        ach.Code,
+			Title:       ach.Title,
+			Description: ach.Description,
+			IconURL:     ach.IconURL,
+			Earned:      owned[ach.Code],
+			Progress:    p,
+		})
+	}
+
+	for _, a := range report.Achievements {
+		if !a.Earned && !a.Progress.Achieved {
+			report.NextUp = append(report.NextUp, a)
+		}
+	}
+	sort.SliceStable(report.NextUp, func(a, b int) bool {
+		return report.NextUp[a].Progress.Ratio > report.NextUp[b].Progress.Ratio
+	})
+	if len(report.NextUp) > nextUpSize {
+		report.NextUp = report.NextUp[:nextUpSize]
+	}
+
+	return report, nil
+}
+
+// HandleStatsDelivered проверяет достижения после отложенной доставки статистики
+func (i *Issuer) HandleStatsDelivered(ctx context.Context, e *models.OutboxEvent) {
+	if e.EventType != models.OutboxEventUserStats {
+		return
+	}
+	var req models.UpdateStatsRequest
+	if err := json.Unmarshal(e.Payload, &req); err != nil {
+		i.logger.Warnf("outbox event %d: bad stats payload: %v", e.ID, err)
+		return
+	}
+	result, err := i.CheckUser(ctx, req.UserID)
+	if err != nil {
+		i.logger.Errorf("failed to check achievements for user %s: %v", req.UserID, err)
+		return
+	}
+	if len(result.Awarded) > 0 {
+		i.logger.Infof("user %s awarded %d achievements after delayed stats update", req.UserID, len(result.Awarded))
+	}
+}
+
+// ReevaluateAchievement проверяет условие достижения у всех пользователей,
+// у которых его ещё нет. Возвращает число выданных.
+func (i *Issuer) ReevaluateAchievement(ctx context.Context, code string) (int, error) {
+	ach, err := i.repo.Achievement(code)
+	if err != nil {
+		return 0, err
+	}
+	if err := i.engine.Validate(ach.Condition); err != nil {
+		return 0, err
+	}
+
+	userIDs, err := i.repo.UsersWithout(ctx, code)
+	if err != nil {
+		return 0, err
+	}
+
+	awarded := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return awarded, ctx.Err()
+		}
+		facts, err := i.loadFacts(ctx, userID)
+		if err != nil {
+			i.logger.Warnf("reevaluate %s: skip user %s: %v", code, userID, err)
+			continue
+		}
+		ok, _ := i.engine.Evaluate(ach.Condition, facts)
+		if !ok {
+			continue
+		}
+		if err := i.repo.Assign(ctx, userID, []string{code}); err != nil {
+			return awarded, err
+		}
+		awarded++
+	}
+	return awarded, nil
+}
+
+// ReevaluateAsync запускает пересчёт в фоне, например после изменения условия
+func (i *Issuer) ReevaluateAsync(code string) {
+	go func() {
+		awarded, err := i.ReevaluateAchievement(context.Background(), code)
+		if err != nil {
+			i.logger.Errorf("failed to reevaluate achievement %s: %v", code, err)
+			return
+		}
+		i.logger.Infof("achievement %s reevaluated: awarded to %d users", code, awarded)
+	}()
+}
@@ -1 +1,59 @@
 package auto_issuance
+
+import "game/models"
+
+// Составные условия: "all" и "any" объединяют conditions, "not" отрицает condition
+const (
+	ConditionAll = "all"
+	ConditionAny = "any"
+	ConditionNot = "not"
+)
+
+// Facts — всё, что известно о пользователе для проверки условий достижений
+type Facts struct {
+	UserID string
+	Stats  models.UserStatisticsResponse
+
+	// Самая длинная серия дней подряд с решёнными головоломками
+	LongestStreakDays int
+	// Решения без подсказок по сложностям
+	NoHintSolves map[string]int
+	// Участие в турнирах; nil, если сервис турниров недоступен —
+	// тогда турнирные условия считаются невыполненными
+	Tournaments *models.TournamentSummary
+}
+
+// Result — итог проверки пользователя: актуальная статистика и новые достижения
+type Result struct {
+	Stats   models.UserStatisticsResponse
+	Awarded []models.Achievement
+}
+
+// Progress — насколько пользователь близок к выполнению условия.
+// Ratio от 0 до 1; для составных условий Parts содержит прогресс частей.
+type Progress struct {
+	Type          string     `json:"type"`
+	Current       int        `json:"current"`
+	Target        int        `json:"target"`
+	LowerIsBetter bool       `json:"lower_is_better,omitempty"` // время, место: цель — не больше Target
+	Ratio         float64    `json:"ratio"`
+	Achieved      bool       `json:"achieved"`
+	Parts         []Progress `json:"parts,omitempty"`
+}
+
+// AchievementProgress — достижение с прогрессом пользователя
+type AchievementProgress struct {
+	Code        string   `json:"code"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	IconURL     string   `json:"icon_url"`
+	Earned      bool     `json:"earned"`
+	Progress    Progress `json:"progress"`
+}
+
+// ProgressReport — прогресс по всем достижениям и ближайшие к получению
+type ProgressReport struct {
+	UserID       string                `json:"user_id"`
+	Achievements []AchievementProgress `json:"achievements"`
+	NextUp       []AchievementProgress `json:"next_up"`
+}
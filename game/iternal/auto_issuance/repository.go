@@ -1 +1,113 @@
 package auto_issuance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"game/database"
+	"game/models"
+	"servicetoken"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Repository собирает факты о пользователе из базы игры
+// и сервисов пользователей и турниров
+type Repository struct {
+	db            *database.Database
+	signer        *servicetoken.Signer
+	usersURL      string
+	tournamentURL string
+	client        *http.Client
+}
+
+func NewRepository(db *database.Database, signer *servicetoken.Signer, usersURL, tournamentURL string) *Repository {
+	return &Repository{
+		db:            db,
+		signer:        signer,
+		usersURL:      usersURL,
+		tournamentURL: tournamentURL,
+		client:        &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// LoadFacts собирает обязательные факты: статистику и историю игр
+func (r *Repository) LoadFacts(ctx context.Context, userID string) (*Facts, error) {
+	facts := &Facts{UserID: userID}
+
+	if err := r.getJSON(ctx, "users", fmt.Sprintf("%s/%s/statistics", r.usersURL, userID), &facts.Stats); err != nil {
+		return nil, fmt.Errorf("fetch stats: %w", err)
+	}
+
+	var err error
+	if facts.LongestStreakDays, err = r.db.GetLongestSolveStreak(ctx, userID); err != nil {
+		return nil, err
+	}
+	if facts.NoHintSolves, err = r.db.CountNoHintSolves(ctx, userID); err != nil {
+		return nil, err
+	}
+	return facts, nil
+}
+
+func (r *Repository) LoadTournaments(ctx context.Context, userID string) (*models.TournamentSummary, error) {
+	var summary models.TournamentSummary
+	if err := r.getJSON(ctx, "tournament", fmt.Sprintf("%s/users/%s/summary", r.tournamentURL, userID), &summary); err != nil {
+		return nil, fmt.Errorf("fetch tournament summary: %w", err)
+	}
+	return &summary, nil
+}
+
+func (r *Repository) getJSON(ctx context.Context, service, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if err := r.signer.SignRequest(req, service, "", ""); err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("service returned %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) Achievements(ctx context.Context) ([]models.Achievement, error) {
+	return r.db.GetAllAchievements(ctx)
+}
+
+func (r *Repository) Achievement(code string) (models.Achievement, error) {
+	return r.db.GetAchievementByCode(code)
+}
+
+func (r *Repository) UserAchievementCodes(ctx context.Context, userID string) (map[string]bool, error) {
+	owned, err := r.db.GetUserAchievements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[string]bool, len(owned))
+	for _, a := range owned {
+		codes[a.Code] = true
+	}
+	return codes, nil
+}
+
+func (r *Repository) UsersWithout(ctx context.Context, code string) ([]string, error) {
+	return r.db.GetUserIDsWithoutAchievement(ctx, code)
+}
+
+func (r *Repository) Assign(ctx context.Context, userID string, codes []string) error {
+	return r.db.AssignAchievements(ctx, userID, codes)
+}
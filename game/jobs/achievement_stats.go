@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"game/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Сколько последних получателей хранить для каждого достижения
+const achievementRecentEarners = 10
+
+// AchievementStatsRefresher периодически пересчитывает агрегат по достижениям,
+// чтобы не считать получателей на каждый запрос
+type AchievementStatsRefresher struct {
+	db           *database.Database
+	logger       *logrus.Logger
+	activeWindow time.Duration
+	interval     time.Duration
+}
+
+func NewAchievementStatsRefresher(db *database.Database, logger *logrus.Logger, activeWindow, interval time.Duration) *AchievementStatsRefresher {
+	return &AchievementStatsRefresher{db: db, logger: logger, activeWindow: activeWindow, interval: interval}
+}
+
+func (r *AchievementStatsRefresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.db.RefreshAchievementStats(ctx, r.activeWindow, achievementRecentEarners); err != nil {
+			r.logger.Errorf("achievement stats: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
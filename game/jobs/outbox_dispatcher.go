@@ -0,0 +1,188 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"game/database"
+	"game/models"
+	"servicetoken"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	outboxBatchSize = 50
+	// Столько событие остаётся за диспетчером, пока идёт отправка
+	outboxLease      = 30 * time.Second
+	outboxBaseDelay  = 5 * time.Second
+	outboxMaxDelay   = time.Hour
+	outboxReqTimeout = 10 * time.Second
+)
+
+// errPermanent — ошибка, повтор которой не поможет (например, 4xx от получателя)
+var errPermanent = errors.New("permanent delivery error")
+
+// OutboxDispatcher доставляет события outbox в другие сервисы с повторами
+// и экспоненциальной задержкой; после maxAttempts неудач событие попадает в dead-letter
+type OutboxDispatcher struct {
+	db          *database.Database
+	logger      *logrus.Logger
+	client      *http.Client
+	signer      *servicetoken.Signer
+	usersURL    string
+	interval    time.Duration
+	maxAttempts int
+	onDelivered func(ctx context.Context, e *models.OutboxEvent)
+}
+
+func NewOutboxDispatcher(db *database.Database, logger *logrus.Logger, signer *servicetoken.Signer, usersURL string, interval time.Duration, maxAttempts int) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		db:          db,
+		logger:      logger,
+		client:      &http.Client{Timeout: outboxReqTimeout},
+		signer:      signer,
+		usersURL:    usersURL,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// OnDelivered задаёт действие после фоновой доставки события
+// (при DispatchNow его выполняет вызывающий сам)
+func (d *OutboxDispatcher) OnDelivered(fn func(ctx context.Context, e *models.OutboxEvent)) {
+	d.onDelivered = fn
+}
+
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchNow сразу пытается доставить событие. При неудаче оно остаётся
+// в очереди и будет доставлено фоновым проходом.
+func (d *OutboxDispatcher) DispatchNow(ctx context.Context, id int64) error {
+	event, err := d.db.ClaimOutboxEvent(ctx, id, outboxLease)
+	if err != nil {
+		return err
+	}
+	if event == nil {
+		return fmt.Errorf("outbox event %d is not pending", id)
+	}
+	return d.dispatch(ctx, event)
+}
+
+func (d *OutboxDispatcher) dispatchPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		events, err := d.db.ClaimOutboxEvents(ctx, outboxBatchSize, outboxLease)
+		if err != nil {
+			d.logger.Errorf("outbox: failed to claim events: %v", err)
+			return
+		}
+		for i := range events {
+			if err := d.dispatch(ctx, &events[i]); err == nil && d.onDelivered != nil {
+				d.onDelivered(ctx, &events[i])
+			}
+		}
+		if len(events) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (d *OutboxDispatcher) dispatch(ctx context.Context, e *models.OutboxEvent) error {
+	deliverErr := d.deliver(ctx, e)
+	if deliverErr == nil {
+		if err := d.db.MarkOutboxDelivered(ctx, e.ID); err != nil {
+			d.logger.Errorf("outbox: failed to mark event %d delivered: %v", e.ID, err)
+		}
+		return nil
+	}
+
+	dead := errors.Is(deliverErr, errPermanent) || e.Attempts >= d.maxAttempts
+	next := time.Now().UTC().Add(backoff(e.Attempts))
+	if err := d.db.MarkOutboxFailed(ctx, e.ID, deliverErr.Error(), next, dead); err != nil {
+		d.logger.Errorf("outbox: failed to reschedule event %d: %v", e.ID, err)
+	}
+
+	if dead {
+		d.logger.Errorf("outbox: event %d (%s) moved to dead-letter after %d attempts: %v", e.ID, e.EventType, e.Attempts, deliverErr)
+	} else {
+		d.logger.Warnf("outbox: event %d (%s) attempt %d failed, retry at %s: %v", e.ID, e.EventType, e.Attempts, next.Format(time.RFC3339), deliverErr)
+	}
+	return deliverErr
+}
+
+func (d *OutboxDispatcher) deliver(ctx context.Context, e *models.OutboxEvent) error {
+	switch e.EventType {
+	case models.OutboxEventUserStats:
+		var req models.UpdateStatsRequest
+		if err := json.Unmarshal(e.Payload, &req); err != nil {
+			return fmt.Errorf("%w: decode payload: %v", errPermanent, err)
+		}
+		url := fmt.Sprintf("%s/%s/statistics", d.usersURL, req.UserID)
+		return d.send(ctx, http.MethodPatch, url, "users", e)
+	default:
+		return fmt.Errorf("%w: unknown event type %q", errPermanent, e.EventType)
+	}
+}
+
+func (d *OutboxDispatcher) send(ctx context.Context, method, url, service string, e *models.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, outboxReqTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(e.Payload))
+	if err != nil {
+		return fmt.Errorf("%w: create request: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", e.IdempotencyKey)
+	if err := d.signer.SignRequest(req, service, "", ""); err != nil {
+		return err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	return err
+}
+
+// backoff — задержка перед следующей попыткой: 5s, 10s, 20s, ... не больше часа
+func backoff(attempts int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempts && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxDelay {
+		delay = outboxMaxDelay
+	}
+	return delay
+}
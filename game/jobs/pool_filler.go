@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"game/database"
+	"game/generator"
+
+	"github.com/sirupsen/logrus"
+)
+
+// За один проход генерируем не больше стольких полей на уровень,
+// чтобы долгие уровни не блокировали остальные
+const poolBatchSize = 10
+
+// После неудачной генерации уровень пропускается, и пауза удваивается
+// с каждой неудачей подряд
+const poolMaxBackoff = 6 * time.Hour
+
+// poolBackoff — неудачи генерации уровня подряд и время следующей попытки
+type poolBackoff struct {
+	failures int
+	until    time.Time
+}
+
+// PoolFiller периодически пополняет запас головоломок каждого уровня
+// до минимального размера
+type PoolFiller struct {
+	db       *database.Database
+	gen      *generator.Generator
+	logger   *logrus.Logger
+	minSize  int
+	interval time.Duration
+
+	// Доступ только из Run
+	backoff map[string]*poolBackoff
+}
+
+func NewPoolFiller(db *database.Database, gen *generator.Generator, logger *logrus.Logger, minSize int, interval time.Duration) *PoolFiller {
+	return &PoolFiller{
+		db:       db,
+		gen:      gen,
+		logger:   logger,
+		minSize:  minSize,
+		interval: interval,
+		backoff:  make(map[string]*poolBackoff),
+	}
+}
+
+func (p *PoolFiller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.fill(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *PoolFiller) fill(ctx context.Context) {
+	counts, err := p.db.CountFieldsByComplexity(ctx)
+	if err != nil {
+		p.logger.Errorf("pool filler: failed to count sudoku fields: %v", err)
+		return
+	}
+
+	for _, difficulty := range generator.Difficulties {
+		missing := p.minSize - counts[difficulty]
+		if missing <= 0 {
+			continue
+		}
+		if missing > poolBatchSize {
+			missing = poolBatchSize
+		}
+		if b := p.backoff[difficulty]; b != nil && time.Now().Before(b.until) {
+			continue
+		}
+
+		added := 0
+		for i := 0; i < missing; i++ {
+			if ctx.Err() != nil {
+				return
+			}
+			puzzle, err := p.gen.Generate(difficulty)
+			if err != nil {
+				delay := p.fail(difficulty)
+				p.logger.Warnf("pool filler: failed to generate %s sudoku, next try in %s: %v", difficulty, delay, err)
+				break
+			}
+			delete(p.backoff, difficulty)
+			field := puzzle.Field()
+			ok, err := p.db.InsertSudokuField(ctx, &field, puzzle.Tags())
+			if err != nil {
+				p.logger.Errorf("pool filler: failed to save %s sudoku: %v", difficulty, err)
+				return
+			}
+			if ok {
+				added++
+			}
+		}
+
+		p.logger.Infof("pool filler: added %d %s sudoku (had %d, min %d)", added, difficulty, counts[difficulty], p.minSize)
+	}
+}
+
+// fail откладывает генерацию уровня и возвращает паузу
+func (p *PoolFiller) fail(difficulty string) time.Duration {
+	b := p.backoff[difficulty]
+	if b == nil {
+		b = &poolBackoff{}
+		p.backoff[difficulty] = b
+	}
+	b.failures++
+
+	delay := p.interval
+	for i := 1; i < b.failures && delay < poolMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > poolMaxBackoff {
+		delay = poolMaxBackoff
+	}
+	b.until = time.Now().Add(delay)
+	return delay
+}
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
 	"game/config"
 	"game/database"
+	"game/generator"
 	"game/handlers"
+	"game/iternal/auto_issuance"
+	"game/jobs"
 	"game/middleware"
+	"game/models"
 	"os"
+	"servicetoken"
+	"servicetoken/guard"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -26,35 +33,101 @@ func main() {
 		logrus.Fatalf("failed to init database: %v", err)
 	}
 
+	gen := generator.New()
+	signer := servicetoken.NewSigner("game", []byte(cfg.ServiceTokenSecret))
+
+	// Фоновое пополнение пула головоломок
+	poolFiller := jobs.NewPoolFiller(db, gen, logger, cfg.PoolMinSize, cfg.PoolCheckInterval)
+	go poolFiller.Run(context.Background())
+
+	// Пересчёт статистики получения достижений
+	statsRefresher := jobs.NewAchievementStatsRefresher(db, logger, cfg.ActivePlayerWindow, cfg.AchievementStatsInterval)
+	go statsRefresher.Run(context.Background())
+
+	// Автоматическая выдача достижений
+	issuer := auto_issuance.NewIssuer(auto_issuance.NewEngine(), auto_issuance.NewRepository(db, signer, cfg.UsersURL, cfg.TournamentURL), logger)
+
+	// Доставка событий outbox в сервис пользователей; после отложенной доставки
+	// статистики достижения проверяются так же, как при обычном решении
+	outbox := jobs.NewOutboxDispatcher(db, logger, signer, cfg.UsersURL, cfg.OutboxInterval, cfg.OutboxMaxAttempts)
+	outbox.OnDelivered(issuer.HandleStatsDelivered)
+	go outbox.Run(context.Background())
+
 	// Инициализация обработчиков
-	gameHandler := handlers.NewGameHandler(db, logger)
+	gameHandler := handlers.NewGameHandler(db, gen, outbox, issuer, logger)
+	autoIssuanceHandler := auto_issuance.NewHandler(issuer, logger)
 
 	// Настройка роутера
 	router := gin.Default()
 
+	// Запросы принимаются только от шлюза и сервиса tournament
+	router.Use(guard.ServiceAuth(servicetoken.NewVerifier("game", []byte(cfg.ServiceTokenSecret), "gateway", "tournament")))
 	router.Use(middleware.ExtractUserIDHeader(cfg))
 
+	admin := guard.RequireRole(models.RoleAdmin)
+	staff := guard.RequireRole(models.RoleModerator, models.RoleAdmin)
+
 	// Sudoku
 	router.GET("/sudoku", gameHandler.GetSudokuByDifficulty)
 	router.GET("/sudoku/all", gameHandler.GetAllSudokuByDifficulty)
+	router.POST("/sudoku/generate", admin, gameHandler.GenerateSudoku)
+	router.POST("/sudoku/import", admin, gameHandler.ImportSudoku)
+	router.GET("/sudoku/export", admin, gameHandler.ExportSudoku)
 	router.GET("/sudoku/:id", gameHandler.GetSudokuByID)
 	router.POST("/sudoku/:id/solved", gameHandler.ReportSolved)
+	router.POST("/sudoku/:id/check", gameHandler.CheckSudokuCells)
+	router.POST("/sudoku/:id/hint", gameHandler.GetSudokuHint)
+	router.POST("/sudoku/:id/tags", staff, gameHandler.TagSudoku)
+	router.DELETE("/sudoku/:id/tags/:name", staff, gameHandler.UntagSudoku)
+
+	// Головоломка дня
+	router.GET("/daily", gameHandler.GetDaily)
+	router.POST("/daily/start", gameHandler.StartDaily)
+	router.GET("/daily/leaderboard", gameHandler.GetDailyLeaderboard)
+	router.GET("/daily/streak", gameHandler.GetDailyStreak)
+
+	// Outbox
+	router.GET("/outbox/dead", admin, gameHandler.GetDeadOutboxEvents)
+	router.POST("/outbox/:id/retry", admin, gameHandler.RetryOutboxEvent)
+
+	// Теги
+	router.GET("/tags", gameHandler.GetAllTags)
+	router.POST("/tags", staff, gameHandler.CreateTag)
+	router.PATCH("/tags/:name", staff, gameHandler.RenameTag)
+	router.DELETE("/tags/:name", staff, gameHandler.DeleteTag)
+
+	// Game sessions
+	router.POST("/sessions", gameHandler.StartSession)
+	router.GET("/sessions", gameHandler.GetMySessions)
+	router.GET("/sessions/:sid", gameHandler.GetSession)
+	router.GET("/sessions/:sid/moves", gameHandler.GetSessionMoves)
+	router.POST("/sessions/:sid/moves", gameHandler.AddSessionMove)
+	router.POST("/sessions/:sid/undo", gameHandler.UndoSessionMove)
+	router.POST("/sessions/:sid/pause", gameHandler.PauseSession)
+	router.POST("/sessions/:sid/resume", gameHandler.ResumeSession)
+	router.POST("/sessions/:sid/complete", gameHandler.CompleteSession)
 
 	// Achievements
 	router.GET("/achievements", gameHandler.GetAllAchievements)
+	router.GET("/achievements/stats", gameHandler.GetAchievementStats)
+	router.GET("/achievements/leaderboard", gameHandler.GetAchievementLeaderboard)
 	router.GET("/achievements/:code", gameHandler.GetAchievementByCode)
-	router.POST("/achievements", gameHandler.CreateAchievement)
-	router.PATCH("/achievements/:code", gameHandler.UpdateAchievement)
-	router.DELETE("/achievements/:code", gameHandler.DeleteAchievement)
+	router.GET("/achievements/:code/stats", gameHandler.GetAchievementStatsByCode)
+	router.POST("/achievements", admin, gameHandler.CreateAchievement)
+	router.PATCH("/achievements/:code", admin, gameHandler.UpdateAchievement)
+	router.DELETE("/achievements/:code", admin, gameHandler.DeleteAchievement)
 	router.GET("/achievements/:code/icon", gameHandler.GetAchievementIcon)
 
 	// User Achievements
 	router.GET("/:id/achievements", gameHandler.GetUserAchievements)
-	router.POST("/:id/achievements", gameHandler.AssignAchievement)
-	router.DELETE("/:id/achievements/:code", gameHandler.DeleteUserAchievement)
+	router.POST("/:id/achievements", staff, gameHandler.AssignAchievement)
+	router.DELETE("/:id/achievements/:code", staff, gameHandler.DeleteUserAchievement)
+	router.GET("/:id/achievements/score", gameHandler.GetAchievementScore)
 
 	// Auto Achievements
-	// router.POST("/:id/achievements/check", gameHandler.CheckAndAssignAchievements)
+	router.POST("/:id/achievements/check", autoIssuanceHandler.CheckAndAssignAchievements)
+	router.GET("/:id/achievements/progress", autoIssuanceHandler.GetAchievementProgress)
+	router.POST("/achievements/:code/reevaluate", admin, autoIssuanceHandler.ReevaluateAchievement)
 
 	// Запуск
 	logger.Infof("Server starting on port %s", cfg.ServerPort)
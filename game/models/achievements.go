@@ -9,15 +9,44 @@ type Achievement struct {
 	Description string               `json:"description"`
 	IconURL     string               `json:"icon_url"`
 	Condition   AchievementCondition `json:"condition"`
+	Series      string               `json:"series,omitempty"` // серия уровней одного условия
+	Tier        string               `json:"tier,omitempty"`   // уровень в серии
+	Points      int                  `json:"points"`
 	CreatedAt   time.Time            `json:"created_at"`
 }
 
+// Уровни серии достижений по возрастанию сложности
+const (
+	TierBronze = "bronze"
+	TierSilver = "silver"
+	TierGold   = "gold"
+)
+
+var AchievementTiers = []string{TierBronze, TierSilver, TierGold}
+
+// TierIndex возвращает позицию уровня в AchievementTiers или -1
+func TierIndex(tier string) int {
+	for i, t := range AchievementTiers {
+		if t == tier {
+			return i
+		}
+	}
+	return -1
+}
+
+const DefaultAchievementPoints = 10
+
 type AchievementCondition struct {
-	Type       string   `json:"type"` // e.g. "solved_count", "best_time", ...
+	Type       string   `json:"type"` // e.g. "solved_count", "best_time", ... или "all", "any", "not"
 	Difficulty string   `json:"difficulty,omitempty"`
 	Count      int      `json:"count,omitempty"`
 	MaxSeconds int      `json:"max_seconds,omitempty"`
+	Seconds    int      `json:"seconds,omitempty"` // для "total_time"
+	Rank       int      `json:"rank,omitempty"`    // для "tournament_rank": место не ниже
 	Levels     []string `json:"levels,omitempty"`
+
+	Conditions []AchievementCondition `json:"conditions,omitempty"` // для "all" и "any"
+	Condition  *AchievementCondition  `json:"condition,omitempty"`  // для "not"
 }
 
 type CreateAchievementForm struct {
@@ -25,12 +54,18 @@ type CreateAchievementForm struct {
 	Title       string `form:"title" binding:"required"`
 	Description string `form:"description"`
 	Condition   string `form:"condition" binding:"required"` // raw JSON-строка
+	Series      string `form:"series"`
+	Tier        string `form:"tier"`
+	Points      *int   `form:"points"`
 }
 
 type UpdateAchievementForm struct {
 	Title       string `form:"title"`
 	Description string `form:"description"`
 	Condition   string `form:"condition"` // JSON-строка, опционально
+	Series      string `form:"series"`
+	Tier        string `form:"tier"`
+	Points      *int   `form:"points"`
 }
 
 type SimpleAchievement struct {
@@ -38,3 +73,35 @@ type SimpleAchievement struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 }
+
+// Достижение редкое, если его получили меньше стольких процентов активных игроков
+const RareAchievementPercent = 5.0
+
+type AchievementEarner struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	EarnedAt time.Time `json:"earned_at"`
+}
+
+// AchievementStats — агрегат по достижению, пересчитываемый фоновой задачей
+type AchievementStats struct {
+	Code              string              `json:"code" db:"code"`
+	Title             string              `json:"title" db:"title"`
+	EarnedCount       int                 `json:"earned_count" db:"earned_count"`
+	ActiveEarnedCount int                 `json:"active_earned_count" db:"active_earned_count"`
+	ActivePlayers     int                 `json:"active_players" db:"active_players"`
+	EarnedPercent     float64             `json:"earned_percent" db:"earned_percent"`
+	Rare              bool                `json:"rare" db:"rare"`
+	FirstEarner       *AchievementEarner  `json:"first_earner,omitempty" db:"-"`
+	RecentEarners     []AchievementEarner `json:"recent_earners" db:"-"`
+	RefreshedAt       time.Time           `json:"refreshed_at" db:"refreshed_at"`
+}
+
+// AchievementScore — сумма очков за полученные достижения и место в рейтинге
+type AchievementScore struct {
+	Rank         int    `json:"rank" db:"rank"`
+	UserID       string `json:"user_id" db:"user_id"`
+	Username     string `json:"username" db:"username"`
+	Score        int    `json:"score" db:"score"`
+	Achievements int    `json:"achievements" db:"achievements"`
+}
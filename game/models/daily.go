@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// DailyAttempt — единственная зачётная попытка пользователя на головоломку дня
+type DailyAttempt struct {
+	Date        time.Time  `db:"date" json:"-"`
+	Difficulty  string     `db:"difficulty" json:"difficulty"`
+	UserID      string     `db:"user_id" json:"-"`
+	SessionID   string     `db:"session_id" json:"session_id"`
+	StartedAt   time.Time  `db:"started_at" json:"started_at"`
+	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	ElapsedMs   *int64     `db:"elapsed_ms" json:"elapsed_ms,omitempty"`
+	Ranked      bool       `db:"ranked" json:"ranked"`
+}
+
+type DailyStreak struct {
+	Current  int        `db:"current_streak" json:"current"`
+	Best     int        `db:"best_streak" json:"best"`
+	LastDate *time.Time `db:"last_date" json:"-"`
+}
+
+type DailyLeaderboardEntry struct {
+	Rank        int       `db:"rank" json:"rank"`
+	UserID      string    `db:"user_id" json:"user_id"`
+	Username    string    `db:"username" json:"username"`
+	ElapsedMs   int64     `db:"elapsed_ms" json:"elapsed_ms"`
+	CompletedAt time.Time `db:"completed_at" json:"completed_at"`
+}
+
+type DailyStartRequest struct {
+	Difficulty string `json:"difficulty" binding:"required"`
+}
+
+type DailyResponse struct {
+	Date       string        `json:"date"`
+	Difficulty string        `json:"difficulty"`
+	Attempt    *DailyAttempt `json:"attempt,omitempty"`
+	Rank       *int          `json:"rank,omitempty"`
+	Streak     DailyStreak   `json:"streak"`
+}
+
+type DailyLeaderboardResponse struct {
+	Date       string                  `json:"date"`
+	Difficulty string                  `json:"difficulty"`
+	Entries    []DailyLeaderboardEntry `json:"entries"`
+}
@@ -14,20 +14,54 @@ type SudokuField struct {
 }
 
 type SudokuResponse struct {
-	ID               string  `json:"id"`
-	InitialField     string  `json:"initial_field"`
-	Solution         string  `json:"solution"`
-	Complexity       string  `json:"complexity"`
-	CreatedAt        string  `json:"created_at"`
-	SolveAttempts    int64   `json:"solve_attempts"`
-	SolvesSuccessful int64   `json:"solves_successful"`
-	AvgSolveTimeMs   int64   `json:"avg_solve_time_ms"`
-	SuccessRate      float64 `json:"success_rate"`
+	ID               string   `json:"id"`
+	InitialField     string   `json:"initial_field,omitempty"`
+	Solution         string   `json:"solution,omitempty"` // только для администраторов
+	Complexity       string   `json:"complexity"`
+	CreatedAt        string   `json:"created_at"`
+	SolveAttempts    int64    `json:"solve_attempts"`
+	SolvesSuccessful int64    `json:"solves_successful"`
+	AvgSolveTimeMs   int64    `json:"avg_solve_time_ms"`
+	SuccessRate      float64  `json:"success_rate"`
+	Tags             []string `json:"tags"`
+	Solved           bool     `json:"solved"` // решал ли его текущий пользователь
 }
 
 type SudokuSolvedRequest struct {
-	UserID      string `json:"user_id"`
-	SolveTimeMs int64  `json:"solve_time_ms"`
+	UserID string `json:"user_id"`
+	Grid   string `json:"grid" binding:"required"` // заполненное поле, 81 символ
+}
+
+type SudokuCheckResponse struct {
+	WrongCells []int `json:"wrong_cells"`
+	Complete   bool  `json:"complete"`
+	ChecksLeft int   `json:"checks_left"`
+}
+
+type SudokuHintResponse struct {
+	Cell      int `json:"cell"`
+	Row       int `json:"row"`
+	Col       int `json:"col"`
+	Value     int `json:"value"`
+	HintsUsed int `json:"hints_used"`
+}
+
+type GenerateSudokuRequest struct {
+	Difficulty string `json:"difficulty" binding:"required"`
+	Count      int    `json:"count"`
+}
+
+type GeneratedSudoku struct {
+	ID         string   `json:"id"`
+	Complexity string   `json:"complexity"`
+	Techniques []string `json:"techniques"`
+	Tags       []string `json:"tags"`
+}
+
+// GenerateSudokuResponse — сохранённые головоломки и число отброшенных повторов
+type GenerateSudokuResponse struct {
+	Generated  []GeneratedSudoku `json:"generated"`
+	Duplicates int               `json:"duplicates"`
 }
 
 type UpdateStatsRequest struct {
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusDead      = "dead"
+)
+
+// Типы событий outbox
+const (
+	OutboxEventUserStats = "user_stats_updated"
+)
+
+// OutboxEvent — событие для другого сервиса, записанное в одной транзакции
+// с изменением, которое его породило
+type OutboxEvent struct {
+	ID             int64      `db:"id"`
+	EventType      string     `db:"event_type"`
+	IdempotencyKey string     `db:"idempotency_key"`
+	Payload        []byte     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LockedUntil    *time.Time `db:"locked_until"`
+	LastError      *string    `db:"last_error"`
+	CreatedAt      time.Time  `db:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+}
+
+type OutboxEventResponse struct {
+	ID             int64           `json:"id"`
+	EventType      string          `json:"event_type"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastError      *string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
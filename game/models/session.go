@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+type SessionStatus string
+
+const (
+	SessionStatusActive    SessionStatus = "active"
+	SessionStatusPaused    SessionStatus = "paused"
+	SessionStatusCompleted SessionStatus = "completed"
+)
+
+type GameSession struct {
+	ID          string            `db:"id"`
+	UserID      string            `db:"user_id"`
+	FieldID     string            `db:"field_id"`
+	Status      SessionStatus     `db:"status"`
+	CurrentGrid string            `db:"current_grid"`
+	PencilMarks map[string]string `db:"-"` // клетка -> цифры-пометки, например "139"
+	StartedAt   time.Time         `db:"started_at"`
+	ResumedAt   *time.Time        `db:"resumed_at"` // начало текущего отрезка игры, nil на паузе
+	ElapsedMs   int64             `db:"elapsed_ms"` // время завершённых отрезков игры
+	ChecksUsed  int               `db:"checks_used"`
+	Ranked      bool              `db:"ranked"` // первая сессия пользователя на поле: время идёт в рекорды
+	CompletedAt *time.Time        `db:"completed_at"`
+	UpdatedAt   time.Time         `db:"updated_at"`
+}
+
+// Elapsed возвращает серверное время решения на момент now
+func (s *GameSession) Elapsed(now time.Time) int64 {
+	elapsed := s.ElapsedMs
+	if s.ResumedAt != nil {
+		elapsed += now.Sub(*s.ResumedAt).Milliseconds()
+	}
+	return elapsed
+}
+
+func (s *GameSession) IsOpen() bool {
+	return s.Status == SessionStatusActive || s.Status == SessionStatusPaused
+}
+
+type GameMove struct {
+	ID              int64     `db:"id" json:"id"`
+	SessionID       string    `db:"session_id" json:"-"`
+	Cell            int       `db:"cell" json:"cell"`
+	Value           int       `db:"value" json:"value"`
+	PrevValue       int       `db:"prev_value" json:"prev_value"`
+	PencilMarks     string    `db:"pencil_marks" json:"pencil_marks"`
+	PrevPencilMarks string    `db:"prev_pencil_marks" json:"prev_pencil_marks"`
+	Undone          bool      `db:"undone" json:"undone"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+}
+
+type StartSessionRequest struct {
+	SudokuID string `json:"sudoku_id" binding:"required"`
+}
+
+type SessionMoveRequest struct {
+	Cell        int    `json:"cell"`
+	Value       int    `json:"value"`        // 0 — стереть цифру
+	PencilMarks string `json:"pencil_marks"` // цифры-пометки клетки, пустая строка — очистить
+}
+
+type SessionResponse struct {
+	ID           string            `json:"id"`
+	SudokuID     string            `json:"sudoku_id"`
+	Status       SessionStatus     `json:"status"`
+	InitialField string            `json:"initial_field,omitempty"`
+	CurrentGrid  string            `json:"current_grid,omitempty"`
+	PencilMarks  map[string]string `json:"pencil_marks"`
+	ElapsedMs    int64             `json:"elapsed_ms"`
+	ChecksUsed   int               `json:"checks_used"`
+	Ranked       bool              `json:"ranked"`
+	StartedAt    time.Time         `json:"started_at"`
+	CompletedAt  *time.Time        `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+type Tag struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+}
+
+type TagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type TagSudokuRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// TournamentSummary — ответ сервиса турниров об участии пользователя
+type TournamentSummary struct {
+	UserID            string             `json:"user_id"`
+	TournamentsJoined int                `json:"tournaments_joined"`
+	Results           []TournamentResult `json:"results"`
+}
+
+type TournamentResult struct {
+	TournamentID string `json:"tournament_id"`
+	Score        int    `json:"score"`
+	Rank         int    `json:"rank"`
+	SolvedCount  int    `json:"solved_count"`
+}
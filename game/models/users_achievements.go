@@ -13,8 +13,13 @@ type AssignAchievementRequest struct {
 }
 
 type AchievementResponse struct {
-	Code        string `json:"code"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	IconURL     string `json:"icon_url"`
+	Code          string  `json:"code" db:"code"`
+	Title         string  `json:"title" db:"title"`
+	Description   string  `json:"description" db:"description"`
+	IconURL       string  `json:"icon_url" db:"icon_url"`
+	Series        string  `json:"series,omitempty" db:"series"`
+	Tier          string  `json:"tier,omitempty" db:"tier"`
+	Points        int     `json:"points" db:"points"`
+	EarnedPercent float64 `json:"earned_percent" db:"earned_percent"`
+	Rare          bool    `json:"rare" db:"rare"`
 }
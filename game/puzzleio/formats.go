@@ -0,0 +1,250 @@
+package puzzleio
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"game/sudoku"
+)
+
+const (
+	FormatLine = "line"
+	FormatSDK  = "sdk"
+	FormatCSV  = "csv"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Record — одна головоломка из входного файла.
+// Line — номер строки, с которой она начинается; Err — ошибка разбора.
+type Record struct {
+	Line       int
+	Puzzle     string
+	Solution   string
+	Difficulty string
+	Err        error
+}
+
+func IsFormat(format string) bool {
+	return format == FormatLine || format == FormatSDK || format == FormatCSV
+}
+
+// FormatFromFilename определяет формат по расширению файла
+func FormatFromFilename(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".sdk":
+		return FormatSDK
+	case ".csv":
+		return FormatCSV
+	default:
+		return FormatLine
+	}
+}
+
+func Read(r io.Reader, format string) ([]Record, error) {
+	switch format {
+	case FormatLine, "":
+		return readLines(r)
+	case FormatSDK:
+		return readSDK(r)
+	case FormatCSV:
+		return readCSV(r)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// readLines: по головоломке из 81 символа на строку, пустые клетки — '.' или '0'.
+// Пустые строки и строки, начинающиеся с '#', пропускаются; после
+// головоломки через пробел может идти комментарий.
+func readLines(r io.Reader) ([]Record, error) {
+	var records []Record
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		puzzle := strings.Fields(line)[0]
+		records = append(records, newRecord(n, puzzle, "", ""))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read lines: %w", err)
+	}
+	return records, nil
+}
+
+// readSDK: формат SadMan Software — 9 строк по 9 символов, строки-метаданные
+// начинаются с '#'. В одном файле допускается несколько полей подряд.
+func readSDK(r io.Reader) ([]Record, error) {
+	var records []Record
+	var rows []string
+	start := 0
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		if len(rows) == 0 {
+			start = n
+		}
+		rows = append(rows, line)
+		if len(rows) == sudoku.Size {
+			records = append(records, newRecord(start, strings.Join(rows, ""), "", ""))
+			rows = rows[:0]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read sdk: %w", err)
+	}
+	if len(rows) > 0 {
+		records = append(records, Record{Line: start, Err: fmt.Errorf("incomplete grid: %d of %d rows", len(rows), sudoku.Size)})
+	}
+	return records, nil
+}
+
+// readCSV: колонки puzzle, difficulty и необязательная solution.
+// Заголовок необязателен: первая строка считается им, если в ней есть
+// известное имя колонки, и тогда порядок колонок берётся из неё.
+func readCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	puzzleCol, difficultyCol, solutionCol := 0, 1, 2
+	var records []Record
+
+	for first := true; ; first = false {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return nil, fmt.Errorf("read csv: %w", err)
+			}
+			records = append(records, Record{Line: perr.StartLine, Err: err})
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+
+		if first && isHeader(row) {
+			puzzleCol, difficultyCol, solutionCol = -1, -1, -1
+			for i, name := range row {
+				switch csvColumn(name) {
+				case "puzzle":
+					puzzleCol = i
+				case "difficulty":
+					difficultyCol = i
+				case "solution":
+					solutionCol = i
+				}
+			}
+			if puzzleCol < 0 {
+				return nil, errors.New("csv header has no puzzle column")
+			}
+			continue
+		}
+
+		records = append(records, newRecord(line, column(row, puzzleCol), column(row, solutionCol), column(row, difficultyCol)))
+	}
+	return records, nil
+}
+
+// isHeader: строка — заголовок, если в ней есть хотя бы одно известное имя колонки
+func isHeader(row []string) bool {
+	for _, name := range row {
+		if csvColumn(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// csvColumn приводит имя колонки заголовка к каноническому; "" — колонка неизвестна
+func csvColumn(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "puzzle", "initial_field":
+		return "puzzle"
+	case "difficulty", "complexity":
+		return "difficulty"
+	case "solution":
+		return "solution"
+	default:
+		return ""
+	}
+}
+
+func column(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+func newRecord(line int, puzzle, solution, difficulty string) Record {
+	rec := Record{Line: line, Puzzle: puzzle, Solution: solution, Difficulty: difficulty}
+	if _, err := sudoku.Parse(puzzle); err != nil {
+		rec.Err = err
+	}
+	return rec
+}
+
+// Write выгружает поля в выбранном формате
+func Write(w io.Writer, format string, records []Record) error {
+	switch format {
+	case FormatLine, "":
+		bw := bufio.NewWriter(w)
+		for _, rec := range records {
+			fmt.Fprintln(bw, toDots(rec.Puzzle))
+		}
+		return bw.Flush()
+	case FormatSDK:
+		bw := bufio.NewWriter(w)
+		for i, rec := range records {
+			if i > 0 {
+				fmt.Fprintln(bw)
+			}
+			fmt.Fprintf(bw, "#D %s\n", rec.Difficulty)
+			grid := toDots(rec.Puzzle)
+			for row := 0; row < sudoku.Size; row++ {
+				fmt.Fprintln(bw, grid[row*sudoku.Size:(row+1)*sudoku.Size])
+			}
+		}
+		return bw.Flush()
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"puzzle", "difficulty", "solution"}); err != nil {
+			return err
+		}
+		for _, rec := range records {
+			if err := cw.Write([]string{rec.Puzzle, rec.Difficulty, rec.Solution}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// ContentType возвращает MIME-тип выгрузки
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func toDots(puzzle string) string {
+	return strings.ReplaceAll(puzzle, "0", ".")
+}
@@ -0,0 +1,146 @@
+package puzzleio
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	easyPuzzle   = "003020600900305001001806400008102900700000008006708200002609500800203009005010300"
+	easySolution = "483921657967345821251876493548132976729564138136798245372689514814253769695417382"
+)
+
+// rows разбивает поле на строки формата sdk
+func rows(puzzle string) string {
+	var b strings.Builder
+	for i := 0; i < len(puzzle); i += 9 {
+		b.WriteString(puzzle[i:i+9] + "\n")
+	}
+	return b.String()
+}
+
+// want — ожидаемая запись: номер строки, поле, решение, сложность и есть ли ошибка
+type want struct {
+	line       int
+	puzzle     string
+	solution   string
+	difficulty string
+	err        bool
+}
+
+func TestRead(t *testing.T) {
+	dots := strings.ReplaceAll(easyPuzzle, "0", ".")
+
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   []want
+	}{
+		{"line with comments and blanks", FormatLine,
+			"# header\n\n" + easyPuzzle + " first\n" + dots + "\n",
+			[]want{{3, easyPuzzle, "", "", false}, {4, dots, "", "", false}}},
+		{"line default format", "", easyPuzzle + "\n", []want{{1, easyPuzzle, "", "", false}}},
+		{"line too short", FormatLine, "123\n" + easyPuzzle + "\n",
+			[]want{{1, "123", "", "", true}, {2, easyPuzzle, "", "", false}}},
+		{"sdk two grids", FormatSDK,
+			"#D easy\n" + rows(easyPuzzle) + "\n[meta]\n" + rows(dots),
+			[]want{{2, easyPuzzle, "", "", false}, {13, dots, "", "", false}}},
+		{"sdk incomplete grid", FormatSDK, rows(easyPuzzle) + "003020600\n",
+			[]want{{1, easyPuzzle, "", "", false}, {10, "", "", "", true}}},
+		{"csv without header", FormatCSV,
+			easyPuzzle + ",easy\n" + easyPuzzle + ",hard," + easySolution + "\n",
+			[]want{{1, easyPuzzle, "", "easy", false}, {2, easyPuzzle, easySolution, "hard", false}}},
+		{"csv header reorders columns", FormatCSV,
+			"Solution, Complexity, initial_field\n" + easySolution + ",medium," + easyPuzzle + "\n",
+			[]want{{2, easyPuzzle, easySolution, "medium", false}}},
+		{"csv header with unknown columns", FormatCSV,
+			"id,puzzle\n7," + easyPuzzle + "\n",
+			[]want{{2, easyPuzzle, "", "", false}}},
+		{"csv first row is data", FormatCSV,
+			"x," + easyPuzzle + "\n",
+			[]want{{1, "x", "", easyPuzzle, true}}},
+		{"csv quoted field spans lines", FormatCSV,
+			"puzzle,difficulty\n" + easyPuzzle + ",\"very\nhard\"\n" + easyPuzzle + ",easy\n",
+			[]want{{2, easyPuzzle, "", "very\nhard", false}, {4, easyPuzzle, "", "easy", false}}},
+		{"csv parse error keeps going", FormatCSV,
+			easyPuzzle + ",\"easy\n",
+			[]want{{1, "", "", "", true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Read() returned %d records, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				r := got[i]
+				if r.Line != w.line || (r.Err != nil) != w.err {
+					t.Errorf("record %d: line %d, err %v; want line %d, err %v", i, r.Line, r.Err, w.line, w.err)
+				}
+				if w.puzzle != "" && (r.Puzzle != w.puzzle || r.Solution != w.solution || r.Difficulty != w.difficulty) {
+					t.Errorf("record %d = %q/%q/%q, want %q/%q/%q", i,
+						r.Puzzle, r.Solution, r.Difficulty, w.puzzle, w.solution, w.difficulty)
+				}
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	if _, err := Read(strings.NewReader("id,difficulty\n"), FormatCSV); err == nil {
+		t.Errorf("csv header without puzzle column accepted")
+	}
+	if _, err := Read(strings.NewReader(""), "xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Read(xml) error = %v, want %v", err, ErrUnknownFormat)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	records := []Record{
+		{Puzzle: easyPuzzle, Solution: easySolution, Difficulty: "easy"},
+		{Puzzle: easyPuzzle, Solution: easySolution, Difficulty: "hard"},
+	}
+	for _, format := range []string{FormatLine, FormatSDK, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, format, records); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Read(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(records) {
+				t.Fatalf("round trip returned %d records, want %d", len(got), len(records))
+			}
+			for i, r := range got {
+				if r.Err != nil || strings.ReplaceAll(r.Puzzle, ".", "0") != easyPuzzle {
+					t.Errorf("record %d = %+v", i, r)
+				}
+				if format == FormatCSV && (r.Difficulty != records[i].Difficulty || r.Solution != easySolution) {
+					t.Errorf("record %d lost csv columns: %+v", i, r)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	tests := map[string]string{
+		"puzzles.SDK": FormatSDK,
+		"a/b.csv":     FormatCSV,
+		"puzzles.txt": FormatLine,
+		"puzzles":     FormatLine,
+	}
+	for name, want := range tests {
+		if got := FormatFromFilename(name); got != want {
+			t.Errorf("FormatFromFilename(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
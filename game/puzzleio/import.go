@@ -0,0 +1,146 @@
+package puzzleio
+
+import (
+	"context"
+	"fmt"
+
+	"game/models"
+	"game/sudoku"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusImported  = "imported"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+)
+
+// Store — то, что нужно импорту от базы данных
+type Store interface {
+	InsertSudokuField(ctx context.Context, field *models.SudokuField, tags []string) (bool, error)
+}
+
+type LineResult struct {
+	Line       int      `json:"line"`
+	Status     string   `json:"status"`
+	ID         string   `json:"id,omitempty"`
+	Difficulty string   `json:"difficulty,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type Report struct {
+	Imported   int          `json:"imported"`
+	Duplicates int          `json:"duplicates"`
+	Invalid    int          `json:"invalid"`
+	Lines      []LineResult `json:"lines"`
+}
+
+// Import проверяет каждую головоломку на единственность решения,
+// вычисляет решение, сложность (если она не указана ни в записи,
+// ни в defaultDifficulty) и автоматические теги и сохраняет,
+// пропуская уже существующие.
+// Ошибка возвращается только при сбое хранилища.
+func Import(ctx context.Context, store Store, records []Record, defaultDifficulty string) (*Report, error) {
+	report := &Report{Lines: make([]LineResult, 0, len(records))}
+
+	for _, rec := range records {
+		res := importRecord(rec, defaultDifficulty)
+		if res.Status == StatusInvalid {
+			report.Invalid++
+			report.Lines = append(report.Lines, res.LineResult)
+			continue
+		}
+
+		ok, err := store.InsertSudokuField(ctx, res.field, res.tags)
+		if err != nil {
+			return report, fmt.Errorf("line %d: %w", rec.Line, err)
+		}
+		if ok {
+			res.Status = StatusImported
+			res.ID = res.field.ID
+			report.Imported++
+		} else {
+			res.Status = StatusDuplicate
+			report.Duplicates++
+		}
+		report.Lines = append(report.Lines, res.LineResult)
+	}
+
+	return report, nil
+}
+
+type recordResult struct {
+	LineResult
+	field *models.SudokuField
+	tags  []string
+}
+
+func importRecord(rec Record, defaultDifficulty string) recordResult {
+	invalid := func(err error) recordResult {
+		return recordResult{LineResult: LineResult{Line: rec.Line, Status: StatusInvalid, Error: err.Error()}}
+	}
+
+	if rec.Err != nil {
+		return invalid(rec.Err)
+	}
+
+	puzzle, err := sudoku.Parse(rec.Puzzle)
+	if err != nil {
+		return invalid(err)
+	}
+
+	solution, err := sudoku.SolveUnique(puzzle)
+	if err != nil {
+		return invalid(err)
+	}
+
+	if rec.Solution != "" {
+		given, err := sudoku.Parse(rec.Solution)
+		if err != nil {
+			return invalid(fmt.Errorf("solution: %w", err))
+		}
+		if given != solution {
+			return invalid(fmt.Errorf("solution does not match the puzzle"))
+		}
+	}
+
+	grade := sudoku.Analyze(puzzle)
+	difficulty := rec.Difficulty
+	if difficulty == "" {
+		difficulty = defaultDifficulty
+	}
+	if difficulty == "" {
+		difficulty = grade.Difficulty
+	}
+	if _, ok := sudoku.DifficultyLevel(difficulty); !ok {
+		return invalid(fmt.Errorf("unknown difficulty %q", difficulty))
+	}
+
+	tags := sudoku.AutoTags(puzzle, grade)
+	return recordResult{
+		LineResult: LineResult{Line: rec.Line, Difficulty: difficulty, Tags: tags},
+		tags:       tags,
+		field: &models.SudokuField{
+			ID:           uuid.New().String(),
+			InitialField: puzzle.String(),
+			Solution:     solution.String(),
+			Complexity:   difficulty,
+		},
+	}
+}
+
+// FromFields готовит записи для выгрузки
+func FromFields(fields []models.SudokuField) []Record {
+	records := make([]Record, 0, len(fields))
+	for i, f := range fields {
+		records = append(records, Record{
+			Line:       i + 1,
+			Puzzle:     f.InitialField,
+			Solution:   f.Solution,
+			Difficulty: f.Complexity,
+		})
+	}
+	return records
+}
@@ -0,0 +1,80 @@
+package puzzleio
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"game/models"
+)
+
+// memStore считает поле дубликатом, если такое уже вставлялось
+type memStore struct {
+	seen map[string]bool
+	err  error
+}
+
+func (s *memStore) InsertSudokuField(ctx context.Context, f *models.SudokuField, tags []string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	if s.seen[f.InitialField] {
+		return false, nil
+	}
+	s.seen[f.InitialField] = true
+	return true, nil
+}
+
+func TestImport(t *testing.T) {
+	wrongSolution := "1" + easySolution[1:]
+
+	tests := []struct {
+		name              string
+		rec               Record
+		defaultDifficulty string
+		wantStatus        string
+		wantDifficulty    string
+	}{
+		{"imported", Record{Line: 1, Puzzle: easyPuzzle, Difficulty: "hard"}, "", StatusImported, "hard"},
+		{"duplicate", Record{Line: 2, Puzzle: easyPuzzle}, "", StatusDuplicate, "easy"},
+		{"parse error from reader", Record{Line: 3, Err: errors.New("incomplete grid")}, "", StatusInvalid, ""},
+		{"bad grid", Record{Line: 4, Puzzle: "123"}, "", StatusInvalid, ""},
+		{"no unique solution", Record{Line: 5, Puzzle: "1" + strings.Repeat("0", 80)}, "", StatusInvalid, ""},
+		{"solution mismatch", Record{Line: 6, Puzzle: easyPuzzle, Solution: wrongSolution}, "", StatusInvalid, ""},
+		{"unknown difficulty", Record{Line: 7, Puzzle: easyPuzzle}, "legendary", StatusInvalid, ""},
+	}
+
+	store := &memStore{seen: map[string]bool{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Import(context.Background(), store, []Record{tt.rec}, tt.defaultDifficulty)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			res := report.Lines[0]
+			if res.Line != tt.rec.Line || res.Status != tt.wantStatus {
+				t.Fatalf("line %d: %s (%s), want line %d: %s", res.Line, res.Status, res.Error, tt.rec.Line, tt.wantStatus)
+			}
+			if tt.wantStatus == StatusInvalid && res.Error == "" {
+				t.Errorf("invalid line without an error message")
+			}
+			if tt.wantDifficulty != "" && res.Difficulty != tt.wantDifficulty {
+				t.Errorf("difficulty = %s, want %s", res.Difficulty, tt.wantDifficulty)
+			}
+		})
+	}
+}
+
+func TestImportStoreError(t *testing.T) {
+	store := &memStore{err: errors.New("connection refused")}
+	records := []Record{{Line: 1, Puzzle: "123"}, {Line: 2, Puzzle: easyPuzzle}}
+
+	report, err := Import(context.Background(), store, records, "")
+	if err == nil {
+		t.Fatal("Import() ignored a store failure")
+	}
+	if report.Invalid != 1 || len(report.Lines) != 1 {
+		t.Errorf("report before failure = %+v", report)
+	}
+}
@@ -0,0 +1,67 @@
+package sudoku
+
+// Step — одно применение приёма при логическом решении.
+// Cell/Value заполнены, если приём поставил цифру, иначе Cell = -1.
+type Step struct {
+	Technique  string `json:"technique"`
+	Cell       int    `json:"cell"`
+	Value      int    `json:"value,omitempty"`
+	Eliminated int    `json:"eliminated,omitempty"`
+}
+
+// Analysis — оценка головоломки и трасса приёмов
+type Analysis struct {
+	Difficulty string   `json:"difficulty"`
+	Techniques []string `json:"techniques"`
+	Steps      []Step   `json:"steps,omitempty"`
+}
+
+// Analyze решает поле логически, применяя приёмы от простых к сложным,
+// и оценивает сложность по самому сложному понадобившемуся приёму.
+// Если приёмов не хватает, головоломка считается inhuman.
+func Analyze(g Grid) Analysis {
+	s := newState(g)
+	level := levelEasy
+	used := make(map[string]bool)
+	var a Analysis
+
+	record := func(step Step, l int) {
+		a.Steps = append(a.Steps, step)
+		if !used[step.Technique] {
+			used[step.Technique] = true
+			a.Techniques = append(a.Techniques, step.Technique)
+		}
+		if l > level {
+			level = l
+		}
+	}
+
+	for !s.solved() {
+		progress := false
+		for _, t := range techniques {
+			s.step = Step{Technique: t.name, Cell: -1}
+			if t.apply(s) {
+				record(s.step, t.level)
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			record(Step{Technique: TechniqueBacktracking, Cell: -1}, levelInhuman)
+			break
+		}
+	}
+
+	a.Difficulty = Difficulties[level]
+	return a
+}
+
+// DifficultyLevel возвращает порядковый номер уровня сложности
+func DifficultyLevel(difficulty string) (int, bool) {
+	for i, d := range Difficulties {
+		if d == difficulty {
+			return i, true
+		}
+	}
+	return 0, false
+}
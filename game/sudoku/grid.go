@@ -0,0 +1,72 @@
+package sudoku
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	Size      = 9
+	BoxSize   = 3
+	CellCount = Size * Size
+)
+
+// Grid — поле 9x9 построчно, 0 означает пустую клетку
+type Grid [CellCount]int
+
+// Parse разбирает строку из 81 символа: цифры 1-9, пустые клетки — '0' или '.'
+func Parse(s string) (Grid, error) {
+	var g Grid
+
+	s = strings.TrimSpace(s)
+	if len(s) != CellCount {
+		return g, fmt.Errorf("grid must contain %d cells, got %d", CellCount, len(s))
+	}
+
+	for i := 0; i < CellCount; i++ {
+		v, ok := CellValue(s[i])
+		if !ok {
+			return g, fmt.Errorf("invalid character %q at position %d", s[i], i)
+		}
+		g[i] = v
+	}
+
+	return g, nil
+}
+
+// CellValue переводит символ клетки в цифру; пустая клетка ('0' или '.') — 0
+func CellValue(ch byte) (int, bool) {
+	switch {
+	case ch == '0' || ch == '.':
+		return 0, true
+	case ch >= '1' && ch <= '9':
+		return int(ch - '0'), true
+	default:
+		return 0, false
+	}
+}
+
+// String возвращает поле в формате initial_field (пустые клетки — '0')
+func (g Grid) String() string {
+	var b strings.Builder
+	b.Grow(CellCount)
+	for _, v := range g {
+		b.WriteByte(byte('0' + v))
+	}
+	return b.String()
+}
+
+func (g Grid) IsComplete() bool {
+	for _, v := range g {
+		if v == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func RowOf(cell int) int { return cell / Size }
+
+func ColOf(cell int) int { return cell % Size }
+
+func BoxOf(cell int) int { return (RowOf(cell)/BoxSize)*BoxSize + ColOf(cell)/BoxSize }
@@ -0,0 +1,33 @@
+package sudoku
+
+import "math/rand"
+
+// WrongCells возвращает заполненные клетки, не совпадающие с решением
+func WrongCells(solution, current Grid) []int {
+	var wrong []int
+	for cell := 0; cell < CellCount; cell++ {
+		if current[cell] != 0 && current[cell] != solution[cell] {
+			wrong = append(wrong, cell)
+		}
+	}
+	return wrong
+}
+
+// PickHint выбирает клетку для подсказки: сначала ошибочно заполненную,
+// иначе случайную пустую. Возвращает -1, если подсказывать нечего.
+func PickHint(solution, current Grid) int {
+	if wrong := WrongCells(solution, current); len(wrong) > 0 {
+		return wrong[0]
+	}
+
+	var empty []int
+	for cell := 0; cell < CellCount; cell++ {
+		if current[cell] == 0 {
+			empty = append(empty, cell)
+		}
+	}
+	if len(empty) == 0 {
+		return -1
+	}
+	return empty[rand.Intn(len(empty))]
+}
@@ -0,0 +1,333 @@
+package sudoku
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+const (
+	// Решается одними одиночками
+	easyPuzzle   = "003020600900305001001806400008102900700000008006708200002609500800203009005010300"
+	easySolution = "483921657967345821251876493548132976729564138136798245372689514814253769695417382"
+
+	// Головоломка А. Инкалы: приёмов анализатора не хватает
+	hardPuzzle   = "800000000003600000070090200050007000000045700000100030001000068008500010090000400"
+	hardSolution = "812753649943682175675491283154237896369845721287169534521974368438526917796318452"
+)
+
+func mustParse(t testing.TB, s string) Grid {
+	t.Helper()
+	g, err := Parse(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return g
+}
+
+func TestSolveUnique(t *testing.T) {
+	// Вторая семёрка в первом столбце
+	conflicting := "7" + easyPuzzle[1:]
+
+	tests := []struct {
+		name     string
+		puzzle   string
+		solution string
+		err      error
+	}{
+		{"easy", easyPuzzle, easySolution, nil},
+		{"hard", hardPuzzle, hardSolution, nil},
+		{"solved", easySolution, easySolution, nil},
+		{"empty", zeros(CellCount), "", ErrMultipleSolutions},
+		{"deadly rectangle", "403921057907345021251876493548132976729564138136798245372689514814253769695417382", "", ErrMultipleSolutions},
+		{"conflicting givens", conflicting, "", ErrNoSolution},
+		{"dead end", "12345678" + zeros(8) + "9" + zeros(CellCount-17), "", ErrNoSolution},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SolveUnique(mustParse(t, tt.puzzle))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SolveUnique() error = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && got.String() != tt.solution {
+				t.Errorf("SolveUnique() = %s, want %s", got, tt.solution)
+			}
+		})
+	}
+}
+
+func TestCountSolutions(t *testing.T) {
+	tests := []struct {
+		name   string
+		puzzle string
+		limit  int
+		want   int
+	}{
+		{"unique", easyPuzzle, 2, 1},
+		{"multiple", zeros(CellCount), 2, 2},
+		{"limit", zeros(CellCount), 5, 5},
+		{"none", "7" + easyPuzzle[1:], 2, 0},
+		{"zero limit", easyPuzzle, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountSolutions(mustParse(t, tt.puzzle), tt.limit); got != tt.want {
+				t.Errorf("CountSolutions() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandomSolved(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		g := RandomSolved(rnd)
+		if conflicts := Verify(Grid{}, g); len(conflicts) > 0 {
+			t.Fatalf("RandomSolved() = %s has conflicts %v", g, conflicts)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	initial := mustParse(t, easyPuzzle)
+	solution := mustParse(t, easySolution)
+
+	// Цифры 4 и 8 в первых двух клетках — не данные
+	swapped := solution
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+
+	givenModified := solution
+	givenModified[2] = 4
+
+	empty := solution
+	empty[0] = 0
+
+	tests := []struct {
+		name      string
+		submitted Grid
+		want      map[int][]string
+	}{
+		{"solution", solution, nil},
+		{"empty cell", empty, map[int][]string{0: {ReasonEmpty}}},
+		{"given modified", givenModified, map[int][]string{
+			2:  {ReasonGivenModified, ReasonRowDuplicate, ReasonColDuplicate, ReasonBoxDuplicate},
+			0:  {ReasonRowDuplicate, ReasonBoxDuplicate},
+			65: {ReasonColDuplicate},
+		}},
+		{"swapped cells", swapped, map[int][]string{
+			0:  {ReasonColDuplicate},
+			1:  {ReasonColDuplicate},
+			28: {ReasonColDuplicate},
+			63: {ReasonColDuplicate},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[int][]string)
+			for _, c := range Verify(initial, tt.submitted) {
+				if c.Row != RowOf(c.Cell) || c.Col != ColOf(c.Cell) || c.Value != tt.submitted[c.Cell] {
+					t.Errorf("conflict %+v does not describe its cell", c)
+				}
+				got[c.Cell] = append(got[c.Cell], c.Reason)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Verify() = %v, want %v", got, tt.want)
+			}
+			for cell, reasons := range tt.want {
+				if !sameReasons(got[cell], reasons) {
+					t.Errorf("Verify() cell %d = %v, want %v", cell, got[cell], reasons)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name       string
+		puzzle     string
+		solution   string
+		difficulty string
+		technique  string
+	}{
+		{"singles", easyPuzzle, easySolution, "easy", TechniqueHiddenSingle},
+		{"backtracking", hardPuzzle, hardSolution, "inhuman", TechniqueBacktracking},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Analyze(mustParse(t, tt.puzzle))
+			if a.Difficulty != tt.difficulty {
+				t.Errorf("Analyze() difficulty = %s, want %s (techniques %v)", a.Difficulty, tt.difficulty, a.Techniques)
+			}
+			if !contains(a.Techniques, tt.technique) {
+				t.Errorf("Analyze() techniques = %v, want %s", a.Techniques, tt.technique)
+			}
+
+			// Каждая поставленная приёмом цифра совпадает с решением
+			solution := mustParse(t, tt.solution)
+			for _, step := range a.Steps {
+				if step.Cell >= 0 && step.Value != solution[step.Cell] {
+					t.Errorf("step %+v contradicts the solution", step)
+				}
+			}
+		})
+	}
+}
+
+// Сложность — уровень самого сложного из применённых приёмов
+func TestAnalyzeGrading(t *testing.T) {
+	levels := map[string]int{TechniqueBacktracking: levelInhuman}
+	for _, tech := range techniques {
+		levels[tech.name] = tech.level
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	seen := make(map[string]bool)
+	for i := 0; i < 30; i++ {
+		solution := RandomSolved(rnd)
+		puzzle := removeGivens(solution, rnd)
+
+		a := Analyze(puzzle)
+		seen[a.Difficulty] = true
+
+		want := levelEasy
+		for _, tech := range a.Techniques {
+			l, ok := levels[tech]
+			if !ok {
+				t.Fatalf("unknown technique %q", tech)
+			}
+			if l > want {
+				want = l
+			}
+		}
+		if a.Difficulty != Difficulties[want] {
+			t.Errorf("Analyze(%s) difficulty = %s, want %s for %v", puzzle, a.Difficulty, Difficulties[want], a.Techniques)
+		}
+		for _, step := range a.Steps {
+			if step.Cell >= 0 && step.Value != solution[step.Cell] {
+				t.Errorf("Analyze(%s) step %+v contradicts the solution", puzzle, step)
+			}
+		}
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected puzzles of several difficulties, got %v", seen)
+	}
+}
+
+func TestAnalyzeSolvedGrid(t *testing.T) {
+	a := Analyze(mustParse(t, easySolution))
+	if a.Difficulty != "easy" || len(a.Steps) != 0 {
+		t.Errorf("Analyze(solved) = %+v, want easy without steps", a)
+	}
+}
+
+func TestDifficultyLevel(t *testing.T) {
+	for i, d := range Difficulties {
+		if got, ok := DifficultyLevel(d); !ok || got != i {
+			t.Errorf("DifficultyLevel(%s) = %d, %v, want %d", d, got, ok, i)
+		}
+	}
+	if _, ok := DifficultyLevel("impossible"); ok {
+		t.Error("DifficultyLevel(impossible) should not be found")
+	}
+}
+
+func BenchmarkSolveUniqueEasy(b *testing.B) {
+	g := mustParse(b, easyPuzzle)
+	for i := 0; i < b.N; i++ {
+		if _, err := SolveUnique(g); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSolveUniqueHard(b *testing.B) {
+	g := mustParse(b, hardPuzzle)
+	for i := 0; i < b.N; i++ {
+		if _, err := SolveUnique(g); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Головоломки, какие строит генератор: клетки убираются, пока решение единственно
+func BenchmarkSolveUniqueGenerated(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	puzzles := make([]Grid, 50)
+	for i := range puzzles {
+		puzzles[i] = removeGivens(RandomSolved(rnd), rnd)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := SolveUnique(puzzles[i%len(puzzles)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCountSolutionsEmpty(b *testing.B) {
+	var g Grid
+	for i := 0; i < b.N; i++ {
+		CountSolutions(g, 2)
+	}
+}
+
+func BenchmarkRandomSolved(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < b.N; i++ {
+		RandomSolved(rnd)
+	}
+}
+
+func BenchmarkAnalyzeHard(b *testing.B) {
+	g := mustParse(b, hardPuzzle)
+	for i := 0; i < b.N; i++ {
+		Analyze(g)
+	}
+}
+
+// removeGivens убирает клетки в случайном порядке, пока решение остаётся единственным
+func removeGivens(solution Grid, rnd *rand.Rand) Grid {
+	g := solution
+	for _, cell := range rnd.Perm(CellCount) {
+		v := g[cell]
+		g[cell] = 0
+		if CountSolutions(g, 2) != 1 {
+			g[cell] = v
+		}
+	}
+	return g
+}
+
+func zeros(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = '0'
+	}
+	return string(b)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sameReasons(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for _, r := range want {
+		if !contains(got, r) {
+			return false
+		}
+	}
+	return true
+}
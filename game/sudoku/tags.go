@@ -0,0 +1,29 @@
+package sudoku
+
+const TagSymmetric = "symmetric"
+
+// IsSymmetric проверяет центральную симметрию расположения подсказок
+func (g Grid) IsSymmetric() bool {
+	for cell := 0; cell < CellCount/2; cell++ {
+		if (g[cell] == 0) != (g[CellCount-1-cell] == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// AutoTags возвращает теги, которые ставятся головоломке автоматически:
+// использованные приёмы (кроме одиночек, нужных почти всегда) и симметрия
+func AutoTags(g Grid, a Analysis) []string {
+	var tags []string
+	for _, t := range a.Techniques {
+		if t == TechniqueHiddenSingle || t == TechniqueNakedSingle {
+			continue
+		}
+		tags = append(tags, t)
+	}
+	if g.IsSymmetric() {
+		tags = append(tags, TagSymmetric)
+	}
+	return tags
+}
@@ -0,0 +1,395 @@
+package sudoku
+
+// Приёмы решения, которыми оценивается сложность головоломки
+const (
+	TechniqueHiddenSingle = "hidden-single"
+	TechniqueNakedSingle  = "naked-single"
+	TechniquePointing     = "pointing"
+	TechniqueBoxLine      = "box-line"
+	TechniqueNakedPair    = "naked-pair"
+	TechniqueHiddenPair   = "hidden-pair"
+	TechniqueNakedTriple  = "naked-triple"
+	TechniqueHiddenTriple = "hidden-triple"
+	TechniqueXWing        = "x-wing"
+	TechniqueSwordfish    = "swordfish"
+	TechniqueXYWing       = "xy-wing"
+	TechniqueBacktracking = "backtracking"
+)
+
+// Уровни сложности по самому сложному требуемому приёму
+const (
+	levelEasy = iota
+	levelMedium
+	levelHard
+	levelVeryHard
+	levelInsane
+	levelInhuman
+)
+
+// Difficulties — уровни сложности в порядке возрастания,
+// те же, что заводятся пользователю в статистике
+var Difficulties = []string{"easy", "medium", "hard", "very_hard", "insane", "inhuman"}
+
+type technique struct {
+	name  string
+	level int
+	apply func(s *state) bool
+}
+
+// Порядок важен: сначала пробуются самые простые приёмы
+var techniques = []technique{
+	{TechniqueHiddenSingle, levelEasy, hiddenSingle},
+	{TechniqueNakedSingle, levelMedium, nakedSingle},
+	{TechniquePointing, levelHard, pointing},
+	{TechniqueBoxLine, levelHard, boxLine},
+	{TechniqueNakedPair, levelHard, func(s *state) bool { return nakedSubset(s, 2) }},
+	{TechniqueHiddenPair, levelHard, func(s *state) bool { return hiddenSubset(s, 2) }},
+	{TechniqueNakedTriple, levelVeryHard, func(s *state) bool { return nakedSubset(s, 3) }},
+	{TechniqueHiddenTriple, levelVeryHard, func(s *state) bool { return hiddenSubset(s, 3) }},
+	{TechniqueXWing, levelVeryHard, func(s *state) bool { return fish(s, 2) }},
+	{TechniqueSwordfish, levelInsane, func(s *state) bool { return fish(s, 3) }},
+	{TechniqueXYWing, levelInsane, xyWing},
+}
+
+// state — поле вместе с кандидатами для логического решения.
+// step накапливает результат последнего применённого приёма.
+type state struct {
+	grid Grid
+	cand [CellCount]Mask
+	step Step
+}
+
+func newState(g Grid) *state {
+	s := &state{grid: g}
+	for cell := 0; cell < CellCount; cell++ {
+		s.cand[cell] = g.Candidates(cell)
+	}
+	return s
+}
+
+func (s *state) place(cell, d int) {
+	s.grid[cell] = d
+	s.cand[cell] = 0
+	for _, p := range Peers[cell] {
+		s.cand[p] &^= Bit(d)
+	}
+	s.step.Cell, s.step.Value = cell, d
+}
+
+func (s *state) eliminate(cell int, m Mask) bool {
+	removed := s.cand[cell] & m
+	if removed == 0 {
+		return false
+	}
+	s.cand[cell] &^= m
+	s.step.Eliminated += removed.Count()
+	return true
+}
+
+func (s *state) solved() bool {
+	return s.grid.IsComplete()
+}
+
+func nakedSingle(s *state) bool {
+	for cell := 0; cell < CellCount; cell++ {
+		if d, ok := s.cand[cell].Single(); ok {
+			s.place(cell, d)
+			return true
+		}
+	}
+	return false
+}
+
+func hiddenSingle(s *state) bool {
+	for _, unit := range Units {
+		for d := 1; d <= Size; d++ {
+			found, count := -1, 0
+			for _, cell := range unit {
+				if s.cand[cell].Has(d) {
+					found = cell
+					count++
+				}
+			}
+			if count == 1 {
+				s.place(found, d)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pointing: кандидат в блоке лежит в одной строке/столбце — убираем его из остальной линии
+func pointing(s *state) bool {
+	for box := 0; box < Size; box++ {
+		unit := Units[2*Size+box]
+		for d := 1; d <= Size; d++ {
+			row, col, n := -1, -1, 0
+			for _, cell := range unit {
+				if !s.cand[cell].Has(d) {
+					continue
+				}
+				if n == 0 {
+					row, col = RowOf(cell), ColOf(cell)
+				} else {
+					if RowOf(cell) != row {
+						row = -1
+					}
+					if ColOf(cell) != col {
+						col = -1
+					}
+				}
+				n++
+			}
+			if n < 2 {
+				continue
+			}
+			progress := false
+			if row >= 0 {
+				for _, cell := range Units[row] {
+					if BoxOf(cell) != box && s.eliminate(cell, Bit(d)) {
+						progress = true
+					}
+				}
+			}
+			if col >= 0 {
+				for _, cell := range Units[Size+col] {
+					if BoxOf(cell) != box && s.eliminate(cell, Bit(d)) {
+						progress = true
+					}
+				}
+			}
+			if progress {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// boxLine: кандидат в строке/столбце лежит в одном блоке — убираем его из остального блока
+func boxLine(s *state) bool {
+	for line := 0; line < 2*Size; line++ {
+		unit := Units[line]
+		for d := 1; d <= Size; d++ {
+			box, n := -1, 0
+			for _, cell := range unit {
+				if !s.cand[cell].Has(d) {
+					continue
+				}
+				if n == 0 {
+					box = BoxOf(cell)
+				} else if BoxOf(cell) != box {
+					box = -1
+				}
+				n++
+			}
+			if n < 2 || box < 0 {
+				continue
+			}
+			progress := false
+			for _, cell := range Units[2*Size+box] {
+				if CellUnits[cell][0] == line || CellUnits[cell][1] == line {
+					continue
+				}
+				if s.eliminate(cell, Bit(d)) {
+					progress = true
+				}
+			}
+			if progress {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nakedSubset: n клеток группы с объединением ровно n кандидатов
+func nakedSubset(s *state, n int) bool {
+	for _, unit := range Units {
+		var open []int
+		for _, cell := range unit {
+			if c := s.cand[cell].Count(); c >= 2 && c <= n {
+				open = append(open, cell)
+			}
+		}
+		found := false
+		combinations(len(open), n, func(idx []int) bool {
+			var union Mask
+			in := make(map[int]bool, n)
+			for _, i := range idx {
+				union |= s.cand[open[i]]
+				in[open[i]] = true
+			}
+			if union.Count() != n {
+				return false
+			}
+			for _, cell := range unit {
+				if !in[cell] && s.eliminate(cell, union) {
+					found = true
+				}
+			}
+			return found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// hiddenSubset: n цифр группы встречаются только в n клетках
+func hiddenSubset(s *state, n int) bool {
+	for _, unit := range Units {
+		var digits []int
+		var where [Size + 1]uint16
+		for d := 1; d <= Size; d++ {
+			for i, cell := range unit {
+				if s.cand[cell].Has(d) {
+					where[d] |= 1 << uint(i)
+				}
+			}
+			if c := popcount(where[d]); c >= 2 && c <= n {
+				digits = append(digits, d)
+			}
+		}
+		found := false
+		combinations(len(digits), n, func(idx []int) bool {
+			var cells uint16
+			var keep Mask
+			for _, i := range idx {
+				cells |= where[digits[i]]
+				keep |= Bit(digits[i])
+			}
+			if popcount(cells) != n {
+				return false
+			}
+			for i, cell := range unit {
+				if cells&(1<<uint(i)) != 0 && s.eliminate(cell, ^keep&AllDigits) {
+					found = true
+				}
+			}
+			return found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// fish: X-Wing (n=2) и Swordfish (n=3) по строкам и по столбцам
+func fish(s *state, n int) bool {
+	for _, byRow := range []bool{true, false} {
+		for d := 1; d <= Size; d++ {
+			var bases []int
+			var positions [Size]uint16
+			for line := 0; line < Size; line++ {
+				for i := 0; i < Size; i++ {
+					if s.cand[fishCell(byRow, line, i)].Has(d) {
+						positions[line] |= 1 << uint(i)
+					}
+				}
+				if c := popcount(positions[line]); c >= 2 && c <= n {
+					bases = append(bases, line)
+				}
+			}
+			found := false
+			combinations(len(bases), n, func(idx []int) bool {
+				var cover uint16
+				isBase := make(map[int]bool, n)
+				for _, i := range idx {
+					cover |= positions[bases[i]]
+					isBase[bases[i]] = true
+				}
+				if popcount(cover) != n {
+					return false
+				}
+				for line := 0; line < Size; line++ {
+					if isBase[line] {
+						continue
+					}
+					for i := 0; i < Size; i++ {
+						if cover&(1<<uint(i)) != 0 && s.eliminate(fishCell(byRow, line, i), Bit(d)) {
+							found = true
+						}
+					}
+				}
+				return found
+			})
+			if found {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fishCell(byRow bool, line, i int) int {
+	if byRow {
+		return line*Size + i
+	}
+	return i*Size + line
+}
+
+// xyWing: опора {x,y} и две «клешни» {x,z}, {y,z} — z исключается из клеток, видящих обе клешни
+func xyWing(s *state) bool {
+	for pivot := 0; pivot < CellCount; pivot++ {
+		pm := s.cand[pivot]
+		if pm.Count() != 2 {
+			continue
+		}
+		for _, a := range Peers[pivot] {
+			am := s.cand[a]
+			if am.Count() != 2 || am == pm || (am&pm).Count() != 1 {
+				continue
+			}
+			for _, b := range Peers[pivot] {
+				bm := s.cand[b]
+				if b == a || bm.Count() != 2 || bm == pm || bm == am || (bm&pm).Count() != 1 {
+					continue
+				}
+				z := am &^ pm
+				if bm&^pm != z || am&pm == bm&pm {
+					continue
+				}
+				progress := false
+				for _, cell := range Peers[a] {
+					if cell != b && cell != pivot && Sees(cell, b) && s.eliminate(cell, z) {
+						progress = true
+					}
+				}
+				if progress {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// combinations перебирает сочетания из n по k, пока fn не вернёт true
+func combinations(n, k int, fn func(idx []int) bool) {
+	if k > n {
+		return
+	}
+	idx := make([]int, k)
+	var rec func(start, depth int) bool
+	rec = func(start, depth int) bool {
+		if depth == k {
+			return fn(idx)
+		}
+		for i := start; i <= n-(k-depth); i++ {
+			idx[depth] = i
+			if rec(i+1, depth+1) {
+				return true
+			}
+		}
+		return false
+	}
+	rec(0, 0)
+}
+
+func popcount(x uint16) int {
+	return Mask(x).Count()
+}
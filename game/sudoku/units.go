@@ -0,0 +1,81 @@
+package sudoku
+
+import "math/bits"
+
+// Units — 27 групп клеток: 9 строк, 9 столбцов, 9 блоков
+var Units [3 * Size][Size]int
+
+// CellUnits — индексы строки, столбца и блока для каждой клетки в Units
+var CellUnits [CellCount][3]int
+
+// Peers — 20 клеток, разделяющих с данной строку, столбец или блок
+var Peers [CellCount][20]int
+
+func init() {
+	for i := 0; i < Size; i++ {
+		Units[i] = rowCells(i)
+		Units[Size+i] = colCells(i)
+		Units[2*Size+i] = boxCells(i)
+	}
+
+	for cell := 0; cell < CellCount; cell++ {
+		CellUnits[cell] = [3]int{RowOf(cell), Size + ColOf(cell), 2*Size + BoxOf(cell)}
+
+		n := 0
+		for other := 0; other < CellCount; other++ {
+			if other != cell && Sees(cell, other) {
+				Peers[cell][n] = other
+				n++
+			}
+		}
+	}
+}
+
+// Sees сообщает, находятся ли клетки в общей строке, столбце или блоке
+func Sees(a, b int) bool {
+	return RowOf(a) == RowOf(b) || ColOf(a) == ColOf(b) || BoxOf(a) == BoxOf(b)
+}
+
+// Mask — набор кандидатов, бит d соответствует цифре d (1-9)
+type Mask uint16
+
+const AllDigits Mask = 0x3FE
+
+func Bit(d int) Mask { return 1 << uint(d) }
+
+func (m Mask) Has(d int) bool { return m&Bit(d) != 0 }
+
+func (m Mask) Count() int { return bits.OnesCount16(uint16(m)) }
+
+// Digits возвращает цифры набора по возрастанию
+func (m Mask) Digits() []int {
+	digits := make([]int, 0, m.Count())
+	for d := 1; d <= Size; d++ {
+		if m.Has(d) {
+			digits = append(digits, d)
+		}
+	}
+	return digits
+}
+
+// Single возвращает цифру, если в наборе ровно один кандидат
+func (m Mask) Single() (int, bool) {
+	if m.Count() != 1 {
+		return 0, false
+	}
+	return bits.TrailingZeros16(uint16(m)), true
+}
+
+// Candidates вычисляет кандидатов для пустой клетки
+func (g Grid) Candidates(cell int) Mask {
+	if g[cell] != 0 {
+		return 0
+	}
+	m := AllDigits
+	for _, p := range Peers[cell] {
+		if v := g[p]; v != 0 {
+			m &^= Bit(v)
+		}
+	}
+	return m
+}
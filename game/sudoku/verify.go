@@ -0,0 +1,95 @@
+package sudoku
+
+const (
+	ReasonEmpty         = "empty_cell"
+	ReasonGivenModified = "given_modified"
+	ReasonRowDuplicate  = "row_duplicate"
+	ReasonColDuplicate  = "column_duplicate"
+	ReasonBoxDuplicate  = "box_duplicate"
+)
+
+// Conflict описывает клетку, из-за которой решение отклонено
+type Conflict struct {
+	Cell   int    `json:"cell"`
+	Row    int    `json:"row"`
+	Col    int    `json:"col"`
+	Value  int    `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// Verify проверяет присланное решение относительно исходных подсказок
+// и ограничений строк, столбцов и блоков. Пустой результат — решение верное.
+func Verify(initial, submitted Grid) []Conflict {
+	var conflicts []Conflict
+
+	add := func(cell int, reason string) {
+		conflicts = append(conflicts, Conflict{
+			Cell:   cell,
+			Row:    RowOf(cell),
+			Col:    ColOf(cell),
+			Value:  submitted[cell],
+			Reason: reason,
+		})
+	}
+
+	for cell := 0; cell < CellCount; cell++ {
+		switch {
+		case initial[cell] != 0 && submitted[cell] != initial[cell]:
+			add(cell, ReasonGivenModified)
+		case submitted[cell] == 0:
+			add(cell, ReasonEmpty)
+		}
+	}
+
+	// Повторы цифр в строках, столбцах и блоках
+	for unit := 0; unit < Size; unit++ {
+		for _, u := range []struct {
+			cells  [Size]int
+			reason string
+		}{
+			{rowCells(unit), ReasonRowDuplicate},
+			{colCells(unit), ReasonColDuplicate},
+			{boxCells(unit), ReasonBoxDuplicate},
+		} {
+			seen := make(map[int][]int, Size)
+			for _, cell := range u.cells {
+				if v := submitted[cell]; v != 0 {
+					seen[v] = append(seen[v], cell)
+				}
+			}
+			for _, cell := range u.cells {
+				if v := submitted[cell]; v != 0 && len(seen[v]) > 1 {
+					add(cell, u.reason)
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+func rowCells(row int) [Size]int {
+	var cells [Size]int
+	for i := 0; i < Size; i++ {
+		cells[i] = row*Size + i
+	}
+	return cells
+}
+
+func colCells(col int) [Size]int {
+	var cells [Size]int
+	for i := 0; i < Size; i++ {
+		cells[i] = i*Size + col
+	}
+	return cells
+}
+
+func boxCells(box int) [Size]int {
+	var cells [Size]int
+	r0 := (box / BoxSize) * BoxSize
+	c0 := (box % BoxSize) * BoxSize
+	for i := 0; i < Size; i++ {
+		cells[i] = (r0+i/BoxSize)*Size + c0 + i%BoxSize
+	}
+	return cells
+}
@@ -4,13 +4,17 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 
+	"gateway/middleware"
 	"gateway/proxy"
+	"gateway/ratelimit"
 )
 
 func main() {
@@ -18,19 +22,26 @@ func main() {
 		log.Println("No .env file found")
 	}
 
-	log.Println("GATEWAY JWT_SECRET:", os.Getenv("JWT_SECRET"))
-
 	r := gin.Default()
 
+	// Шлюз принимает запросы напрямую от клиентов, поэтому по умолчанию
+	// X-Forwarded-For не доверяем: иначе лимит по IP легко обойти
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Middleware: CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization", "X-Request-ID", "X-User-Role"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 	}))
 
+	// Middleware: identity headers are set only by the gateway
+	r.Use(middleware.StripIdentityHeaders())
+
 	// Middleware: simple request log
 	r.Use(func(c *gin.Context) {
 		log.Printf("[%s] %s", c.Request.Method, c.Request.URL.Path)
@@ -49,11 +60,19 @@ func main() {
 	gameServiceURL := mustParse("GAME_SERVICE_URL")
 	tournamentServiceURL := mustParse("TOURNAMENT_SERVICE_URL")
 
+	// Подпись запросов к сервисам: без неё они отклоняют запросы
+	if os.Getenv("SERVICE_TOKEN_SECRET") == "" {
+		log.Fatal("missing SERVICE_TOKEN_SECRET")
+	}
+
+	// Rate limiting: корзины в памяти, для нескольких экземпляров шлюза нужен общий ratelimit.Store
+	limit := middleware.RateLimit(ratelimit.NewMemoryStore(), rateLimitRules(), logger)
+
 	// Register proxies
-	proxy.RegisterAuthProxy(r, authServiceURL, logger)
-	proxy.RegisterUsersProxy(r, usersServiceURL, logger)
-	proxy.RegisterGameProxy(r, gameServiceURL, logger)
-	proxy.RegisterTournamentProxy(r, tournamentServiceURL, logger)
+	proxy.RegisterAuthProxy(r, authServiceURL, logger, limit)
+	proxy.RegisterUsersProxy(r, usersServiceURL, logger, limit)
+	proxy.RegisterGameProxy(r, gameServiceURL, logger, limit)
+	proxy.RegisterTournamentProxy(r, tournamentServiceURL, logger, limit)
 
 	// Start server
 	port := os.Getenv("GATEWAY_PORT")
@@ -77,3 +96,42 @@ func mustParse(env string) *url.URL {
 	}
 	return parsed
 }
+
+// rateLimitRules — лимиты групп маршрутов; значения по умолчанию переопределяются
+// переменными вида RATE_LIMIT_AUTH=20/1m
+func rateLimitRules() []ratelimit.Rule {
+	defaults := []struct {
+		prefix, name, env string
+		limit             int
+	}{
+		{"/auth", "auth", "RATE_LIMIT_AUTH", 20},
+		{"/users", "users", "RATE_LIMIT_USERS", 120},
+		{"/game", "game", "RATE_LIMIT_GAME", 300},
+		{"/game/sudoku", "game_sudoku", "RATE_LIMIT_GAME_SUDOKU", 600},
+		{"/tournaments", "tournaments", "RATE_LIMIT_TOURNAMENTS", 120},
+	}
+
+	rules := make([]ratelimit.Rule, 0, len(defaults))
+	for _, d := range defaults {
+		policy := ratelimit.Policy{Name: d.name, Limit: d.limit, Period: time.Minute}
+		if raw := os.Getenv(d.env); raw != "" {
+			var err error
+			if policy, err = ratelimit.ParsePolicy(d.name, raw); err != nil {
+				log.Fatalf("invalid %s: %v", d.env, err)
+			}
+		}
+		rules = append(rules, ratelimit.Rule{Prefix: d.prefix, Policy: policy})
+	}
+	return rules
+}
+
+// trustedProxies — адреса балансировщиков перед шлюзом из TRUSTED_PROXIES через запятую
+func trustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
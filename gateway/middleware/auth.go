@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"errors"
+	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"servicetoken"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -14,10 +18,35 @@ import (
 type JWTClaims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	// Версия токенов пользователя на момент выдачи
+	TokenVersion int `json:"ver"`
+	// Вход подтверждён вторым фактором
+	MFA bool `json:"mfa"`
 	jwt.RegisteredClaims
 }
 
-func AuthRequired(logger *logrus.Logger) gin.HandlerFunc {
+// authErrorKey — ключ контекста с отказом authenticate
+const authErrorKey = "auth_error"
+
+type authError struct {
+	status  int
+	message string
+}
+
+// AuthRequired возвращает цепочку проверки access-токена. limit выполняется
+// между проверкой и отказом: запросы с действительным токеном ограничиваются
+// по пользователю, а с отсутствующим или недействительным — по IP клиента,
+// так что перебор токенов тоже упирается в лимит.
+func AuthRequired(logger *logrus.Logger, limit gin.HandlerFunc) []gin.HandlerFunc {
+	return []gin.HandlerFunc{authenticate(logger), limit, rejectUnauthenticated}
+}
+
+// authenticate проверяет токен; при отказе запрос не прерывается,
+// а помечается для rejectUnauthenticated
+func authenticate(logger *logrus.Logger) gin.HandlerFunc {
+	// Модераторы и администраторы без второго фактора получают права игрока
+	requireStaffMFA := os.Getenv("REQUIRE_STAFF_MFA") == "true"
+
 	return func(c *gin.Context) {
 		start := time.Now()
 
@@ -32,36 +61,53 @@ func AuthRequired(logger *logrus.Logger) gin.HandlerFunc {
 		// 🔐 Authorization
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			unauthorized(c, "Authorization header is required")
+			deny(c, http.StatusUnauthorized, "Authorization header is required")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			unauthorized(c, "Invalid authorization header format. Expected: Bearer <token>")
+			deny(c, http.StatusUnauthorized, "Invalid authorization header format. Expected: Bearer <token>")
 			return
 		}
 
 		tokenStr := parts[1]
 		claims := &JWTClaims{}
 
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		// Подпись проверяем открытым ключом из JWKS сервиса auth
+		token, err := jwt.ParseWithClaims(tokenStr, claims, jwks.keyfunc(c.Request.Context()),
+			jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}))
 
 		if err != nil || !token.Valid {
-			unauthorized(c, "Invalid or expired token")
+			deny(c, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
 		if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
-			unauthorized(c, "Token has expired")
+			deny(c, http.StatusUnauthorized, "Token has expired")
+			return
+		}
+
+		// Отзыв токенов: смена пароля, выход со всех устройств или удаление пользователя
+		current, err := versions.current(c.Request.Context(), claims.UserID)
+		if err != nil && !errors.Is(err, errUserNotFound) {
+			logger.Warnf("failed to check token version for user %s: %v", claims.UserID, err)
+			deny(c, http.StatusServiceUnavailable, "Unable to verify token")
+			return
+		}
+		if err != nil || claims.TokenVersion != current {
+			deny(c, http.StatusUnauthorized, "Token has been revoked")
 			return
 		}
 
+		// Токены, выданные до появления ролей
+		if claims.Role == "" {
+			claims.Role = "player"
+		}
+		if requireStaffMFA && claims.Role != "player" && !claims.MFA {
+			claims.Role = "player"
+		}
+
 		// Прокидываем user_id и роль
 		c.Set("user_id", claims.UserID)
 		c.Set("user_role", claims.Role)
@@ -85,6 +131,29 @@ func AuthRequired(logger *logrus.Logger) gin.HandlerFunc {
 	}
 }
 
-func unauthorized(c *gin.Context, message string) {
-	c.AbortWithStatusJSON(401, gin.H{"error": message})
+// deny помечает запрос как не прошедший проверку и передаёт его дальше по цепочке
+func deny(c *gin.Context, status int, message string) {
+	c.Set(authErrorKey, authError{status: status, message: message})
+	c.Next()
+}
+
+// rejectUnauthenticated отвечает отказом, сохранённым authenticate
+func rejectUnauthenticated(c *gin.Context) {
+	if v, ok := c.Get(authErrorKey); ok {
+		err := v.(authError)
+		c.AbortWithStatusJSON(err.status, gin.H{"error": err.message})
+		return
+	}
+	c.Next()
+}
+
+// StripIdentityHeaders удаляет заголовки личности, пришедшие от клиента:
+// сервисы доверяют им, поэтому выставлять их может только шлюз
+func StripIdentityHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Header.Del("X-User-ID")
+		c.Request.Header.Del("X-User-Role")
+		c.Request.Header.Del(servicetoken.Header)
+		c.Next()
+	}
 }
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Как долго доверяем загруженному набору ключей
+	jwksTTL = 5 * time.Minute
+	// Неизвестный kid вызывает перезагрузку не чаще этого: защита от перебора kid
+	jwksMinRefresh = 30 * time.Second
+)
+
+// jwksCache хранит открытые ключи сервиса auth; секрет подписи шлюзу не нужен.
+// Загрузка идёт без блокировки кэша и одна на всех: пока она длится,
+// запросы с известным kid проверяются по уже загруженным ключам.
+type jwksCache struct {
+	mu          sync.Mutex
+	keys        map[string]jwksKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	client      *http.Client
+	flight      singleflight.Group
+}
+
+type jwksKey struct {
+	alg string
+	pub crypto.PublicKey
+}
+
+var jwks = &jwksCache{client: &http.Client{Timeout: 5 * time.Second}}
+
+// keyfunc выбирает ключ по kid из заголовка токена
+func (j *jwksCache) keyfunc(ctx context.Context) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, err := j.lookup(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != k.alg {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return k.pub, nil
+	}
+}
+
+func (j *jwksCache) lookup(ctx context.Context, kid string) (jwksKey, error) {
+	j.mu.Lock()
+	k, ok := j.keys[kid]
+	age := time.Since(j.fetchedAt)
+	// Неудачная загрузка тоже повторяется не чаще jwksMinRefresh; пока загрузка
+	// идёт, attemptedAt старый, и новые запросы присоединяются к ней
+	canRefresh := time.Since(j.attemptedAt) > jwksMinRefresh
+	j.mu.Unlock()
+
+	switch {
+	case ok && age > jwksTTL && canRefresh:
+		// Устаревший набор обновляем в фоне, отвечая по кэшу
+		j.flight.DoChan("jwks", j.refresh)
+		return k, nil
+	case ok:
+		return k, nil
+	// После ротации токены приходят с новым kid раньше, чем истечёт TTL
+	case age > jwksMinRefresh && canRefresh:
+		select {
+		case res := <-j.flight.DoChan("jwks", j.refresh):
+			if res.Err != nil {
+				return jwksKey{}, res.Err
+			}
+		case <-ctx.Done():
+			return jwksKey{}, ctx.Err()
+		}
+		j.mu.Lock()
+		k, ok = j.keys[kid]
+		j.mu.Unlock()
+	}
+	if !ok {
+		return jwksKey{}, fmt.Errorf("unknown key %q", kid)
+	}
+	return k, nil
+}
+
+// refresh загружает ключи для всех ожидающих сразу, поэтому не зависит
+// от контекста отдельного запроса: время ограничивает таймаут клиента
+func (j *jwksCache) refresh() (interface{}, error) {
+	keys, err := j.fetch()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.attemptedAt = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	j.keys, j.fetchedAt = keys, j.attemptedAt
+	return nil, nil
+}
+
+func (j *jwksCache) fetch() (map[string]jwksKey, error) {
+	req, err := http.NewRequest(http.MethodGet, os.Getenv("AUTH_SERVICE_URL")+"/.well-known/jwks.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ServiceSigner().SignRequest(req, "auth", "", ""); err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: auth service responded with %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			KeyID string `json:"kid"`
+			Type  string `json:"kty"`
+			Alg   string `json:"alg"`
+			Curve string `json:"crv"`
+			X     string `json:"x"`
+			N     string `json:"n"`
+			E     string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, k := range set.Keys {
+		switch {
+		case k.Type == "OKP" && k.Curve == "Ed25519" && k.Alg == jwt.SigningMethodEdDSA.Alg():
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("jwks: invalid Ed25519 key %q", k.KeyID)
+			}
+			keys[k.KeyID] = jwksKey{alg: k.Alg, pub: ed25519.PublicKey(x)}
+		case k.Type == "RSA" && k.Alg == jwt.SigningMethodRS256.Alg():
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				return nil, fmt.Errorf("jwks: invalid RSA key %q", k.KeyID)
+			}
+			keys[k.KeyID] = jwksKey{alg: k.Alg, pub: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}}
+		}
+	}
+
+	return keys, nil
+}
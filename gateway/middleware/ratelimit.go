@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gateway/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimit ограничивает частоту запросов по правилу с самым длинным подходящим
+// префиксом пути. Ключ — пользователь, если до этого отработал AuthRequired,
+// иначе IP клиента. Если хранилище недоступно, запрос пропускается.
+func RateLimit(store ratelimit.Store, rules []ratelimit.Rule, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, ok := ratelimit.Match(rules, c.Request.URL.Path)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + c.ClientIP()
+		if userID := c.GetString("user_id"); userID != "" {
+			key = "user:" + userID
+		}
+
+		res, err := store.Take(c.Request.Context(), policy.Name+":"+key, policy, time.Now())
+		if err != nil {
+			logger.Warnf("rate limit store failed for %s: %v", key, err)
+			c.Next()
+			return
+		}
+
+		// Заголовки по draft-ietf-httpapi-ratelimit-headers
+		h := c.Writer.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", seconds(res.Reset))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", policy.Limit, seconds(policy.Period)))
+
+		if !res.Allowed {
+			h.Set("Retry-After", seconds(res.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// seconds округляет длительность вверх до целых секунд
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Сколько шлюз доверяет закэшированной версии токенов: столько максимум
+// отозванный access-токен может ещё приниматься
+const tokenVersionTTL = 30 * time.Second
+
+// Больше записей кэш не держит: при переполнении просто начинаем заново
+const tokenVersionCacheSize = 10000
+
+var errUserNotFound = errors.New("user not found")
+
+type cachedVersion struct {
+	version   int
+	fetchedAt time.Time
+}
+
+// tokenVersions — кэш текущих версий токенов пользователей из сервиса users
+type tokenVersions struct {
+	mu     sync.Mutex
+	cache  map[string]cachedVersion
+	client *http.Client
+}
+
+var versions = &tokenVersions{
+	cache:  make(map[string]cachedVersion),
+	client: &http.Client{Timeout: 5 * time.Second},
+}
+
+func (v *tokenVersions) current(ctx context.Context, userID string) (int, error) {
+	v.mu.Lock()
+	cached, ok := v.cache[userID]
+	v.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < tokenVersionTTL {
+		return cached.version, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, os.Getenv("USERS_SERVICE_URL")+"/"+userID+"/auth", nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := ServiceSigner().SignRequest(req, "users", "", ""); err != nil {
+		return 0, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, errUserNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("users service responded with %d", resp.StatusCode)
+	}
+	var state struct {
+		TokenVersion int `json:"token_version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return 0, fmt.Errorf("decode token version: %w", err)
+	}
+
+	v.mu.Lock()
+	if len(v.cache) >= tokenVersionCacheSize {
+		v.cache = make(map[string]cachedVersion)
+	}
+	v.cache[userID] = cachedVersion{version: state.TokenVersion, fetchedAt: time.Now()}
+	v.mu.Unlock()
+	return state.TokenVersion, nil
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"os"
+	"sync"
+
+	"servicetoken"
+)
+
+var (
+	signerOnce sync.Once
+	signer     *servicetoken.Signer
+)
+
+// ServiceSigner подписывает запросы шлюза к сервисам общим секретом SERVICE_TOKEN_SECRET
+func ServiceSigner() *servicetoken.Signer {
+	signerOnce.Do(func() {
+		signer = servicetoken.NewSigner("gateway", []byte(os.Getenv("SERVICE_TOKEN_SECRET")))
+	})
+	return signer
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func RegisterAuthProxy(r *gin.Engine, authServiceURL *url.URL, logger *logrus.Logger) {
+func RegisterAuthProxy(r *gin.Engine, authServiceURL *url.URL, logger *logrus.Logger, limit gin.HandlerFunc) {
 	proxy := httputil.NewSingleHostReverseProxy(authServiceURL)
 
 	// Кастомный director
@@ -38,11 +38,14 @@ func RegisterAuthProxy(r *gin.Engine, authServiceURL *url.URL, logger *logrus.Lo
 		http.Error(rw, "auth service unavailable: "+err.Error(), http.StatusBadGateway)
 	}
 
-	// Прокси без AuthRequired
-	r.Any("/auth/*path", func(c *gin.Context) {
+	// Прокси без AuthRequired; лимит считается по IP
+	r.Any("/auth/*path", limit, func(c *gin.Context) {
 		if reqID, ok := c.Get("request_id"); ok {
 			c.Request.Header.Set("X-Request-ID", reqID.(string))
 		}
+		if !signRequest(c, "auth") {
+			return
+		}
 		proxy.ServeHTTP(c.Writer, c.Request)
 	})
 }
@@ -12,7 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func RegisterGameProxy(r *gin.Engine, gameServiceURL *url.URL, logger *logrus.Logger) {
+func RegisterGameProxy(r *gin.Engine, gameServiceURL *url.URL, logger *logrus.Logger, limit gin.HandlerFunc) {
 	proxy := httputil.NewSingleHostReverseProxy(gameServiceURL)
 
 	// Кастомный Director
@@ -38,7 +38,8 @@ func RegisterGameProxy(r *gin.Engine, gameServiceURL *url.URL, logger *logrus.Lo
 		http.Error(w, "game service unavailable: "+err.Error(), http.StatusBadGateway)
 	}
 
-	r.Any("/game/*path", middleware.AuthRequired(logger), func(c *gin.Context) {
+	game := r.Group("/game", middleware.AuthRequired(logger, limit)...)
+	game.Any("/*path", func(c *gin.Context) {
 		// Прокидываем user_id
 		if userID, exists := c.Get("user_id"); exists {
 			c.Request.Header.Set("X-User-ID", userID.(string))
@@ -49,6 +50,9 @@ func RegisterGameProxy(r *gin.Engine, gameServiceURL *url.URL, logger *logrus.Lo
 		if reqID, ok := c.Get("request_id"); ok {
 			c.Request.Header.Set("X-Request-ID", reqID.(string))
 		}
+		if !signRequest(c, "game") {
+			return
+		}
 
 		proxy.ServeHTTP(c.Writer, c.Request)
 	})
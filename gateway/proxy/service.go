@@ -0,0 +1,20 @@
+package proxy
+
+import (
+	"net/http"
+
+	"gateway/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signRequest добавляет токен шлюза для сервиса service от имени текущего пользователя.
+// false — ответ с ошибкой уже отправлен.
+func signRequest(c *gin.Context, service string) bool {
+	err := middleware.ServiceSigner().SignRequest(c.Request, service, c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to sign request"})
+		return false
+	}
+	return true
+}
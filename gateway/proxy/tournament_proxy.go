@@ -12,7 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func RegisterTournamentProxy(r *gin.Engine, tournamentServiceURL *url.URL, logger *logrus.Logger) {
+func RegisterTournamentProxy(r *gin.Engine, tournamentServiceURL *url.URL, logger *logrus.Logger, limit gin.HandlerFunc) {
 	proxy := httputil.NewSingleHostReverseProxy(tournamentServiceURL)
 
 	proxy.Director = func(req *http.Request) {
@@ -34,7 +34,8 @@ func RegisterTournamentProxy(r *gin.Engine, tournamentServiceURL *url.URL, logge
 		http.Error(w, "tournament service unavailable: "+err.Error(), http.StatusBadGateway)
 	}
 
-	r.Any("/tournaments/*path", middleware.AuthRequired(logger), func(c *gin.Context) {
+	tournaments := r.Group("/tournaments", middleware.AuthRequired(logger, limit)...)
+	tournaments.Any("/*path", func(c *gin.Context) {
 		// Headers
 		if userID, ok := c.Get("user_id"); ok {
 			c.Request.Header.Set("X-User-ID", userID.(string))
@@ -45,6 +46,9 @@ func RegisterTournamentProxy(r *gin.Engine, tournamentServiceURL *url.URL, logge
 		if reqID, ok := c.Get("request_id"); ok {
 			c.Request.Header.Set("X-Request-ID", reqID.(string))
 		}
+		if !signRequest(c, "tournament") {
+			return
+		}
 
 		proxy.ServeHTTP(c.Writer, c.Request)
 	})
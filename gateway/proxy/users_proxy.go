@@ -14,6 +14,9 @@ import (
 
 func ProxyWithUserHeaders(proxy *httputil.ReverseProxy) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !signRequest(c, "users") {
+			return
+		}
 		if userID, ok := c.Get("user_id"); ok {
 			c.Request.Header.Set("X-User-ID", userID.(string))
 		}
@@ -27,7 +30,7 @@ func ProxyWithUserHeaders(proxy *httputil.ReverseProxy) gin.HandlerFunc {
 	}
 }
 
-func RegisterUsersProxy(r *gin.Engine, usersServiceURL *url.URL, logger *logrus.Logger) {
+func RegisterUsersProxy(r *gin.Engine, usersServiceURL *url.URL, logger *logrus.Logger, limit gin.HandlerFunc) {
 	proxy := httputil.NewSingleHostReverseProxy(usersServiceURL)
 
 	proxy.Director = func(req *http.Request) {
@@ -49,19 +52,19 @@ func RegisterUsersProxy(r *gin.Engine, usersServiceURL *url.URL, logger *logrus.
 	}
 
 	// -------- 📣 Публичные маршруты --------
-	public := r.Group("/users")
-	public.Any("/check-username", gin.WrapH(proxy))
-	public.Any("/check-email", gin.WrapH(proxy))
-	public.POST("/", gin.WrapH(proxy))
-	public.POST("/auth", gin.WrapH(proxy))
+	public := r.Group("/users", limit)
+	public.Any("/check-username", ProxyWithUserHeaders(proxy))
+	public.Any("/check-email", ProxyWithUserHeaders(proxy))
+	// Создание пользователя и проверка пароля доступны только через сервис auth
 
 	// -------- 🔒 Защищённые маршруты с проксированием X-User-ID и др. --------
-	protected := r.Group("/users", middleware.AuthRequired(logger))
+	protected := r.Group("/users", middleware.AuthRequired(logger, limit)...)
 
 	protected.GET("/", ProxyWithUserHeaders(proxy))
 	protected.GET("/:id", ProxyWithUserHeaders(proxy))
 	protected.PATCH("/:id", ProxyWithUserHeaders(proxy))
 	protected.DELETE("/:id", ProxyWithUserHeaders(proxy))
+	protected.PUT("/:id/role", ProxyWithUserHeaders(proxy))
 
 	protected.GET("/me", ProxyWithUserHeaders(proxy))
 	protected.GET("/me/info", ProxyWithUserHeaders(proxy))
@@ -72,5 +75,4 @@ func RegisterUsersProxy(r *gin.Engine, usersServiceURL *url.URL, logger *logrus.
 	protected.GET("/:id/avatar", ProxyWithUserHeaders(proxy))
 
 	protected.GET("/:id/statistics", ProxyWithUserHeaders(proxy))
-	protected.PATCH("/:id/statistics", ProxyWithUserHeaders(proxy))
 }
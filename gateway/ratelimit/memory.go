@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Полные корзины удаляются не чаще этого: они ничем не отличаются от новых
+const sweepInterval = time.Minute
+
+// MemoryStore хранит корзины в памяти процесса: для одного экземпляра шлюза и тестов
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	policy  Policy
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(p.Limit), updated: now}
+	}
+	b.tokens = refill(p, b.tokens, now.Sub(b.updated))
+	b.updated = now
+	b.policy = p
+
+	res := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = untilTokens(p, b.tokens, 1)
+	}
+	s.buckets[key] = b
+
+	res.Remaining = int(b.tokens)
+	res.Reset = untilTokens(p, b.tokens, float64(p.Limit))
+	return res, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if refill(b.policy, b.tokens, now.Sub(b.updated)) >= float64(b.policy.Limit) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,101 @@
+// Package ratelimit — ограничение частоты запросов по алгоритму token bucket.
+//
+// У каждого ключа (пользователь или IP) своя корзина на Limit запросов, которая
+// равномерно пополняется до полной за Period. Запрос забирает один токен;
+// пустая корзина означает отказ.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy — лимит группы маршрутов
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// Rule применяет политику к путям с префиксом Prefix
+type Rule struct {
+	Prefix string
+	Policy Policy
+}
+
+// Result — состояние корзины после запроса
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Через сколько корзина снова будет полной
+	Reset time.Duration
+	// Через сколько появится токен для следующего запроса (при отказе)
+	RetryAfter time.Duration
+}
+
+// Store хранит корзины. MemoryStore подходит для одного экземпляра шлюза;
+// при нескольких нужна общая реализация (например, поверх Redis), атомарно
+// выполняющая Take.
+type Store interface {
+	Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error)
+}
+
+// ParsePolicy разбирает лимит вида "20/1m": 20 запросов за минуту
+func ParsePolicy(name, s string) (Policy, error) {
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q: expected <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid request count", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid period", s)
+	}
+	return Policy{Name: name, Limit: n, Period: d}, nil
+}
+
+// Match выбирает правило с самым длинным подходящим префиксом
+func Match(rules []Rule, path string) (Policy, bool) {
+	var best *Rule
+	for i := range rules {
+		r := &rules[i]
+		if !hasPathPrefix(path, r.Prefix) {
+			continue
+		}
+		if best == nil || len(r.Prefix) > len(best.Prefix) {
+			best = r
+		}
+	}
+	if best == nil {
+		return Policy{}, false
+	}
+	return best.Policy, true
+}
+
+// hasPathPrefix — префикс совпадает по границе сегмента: /game не подходит к /gamer
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// refill возвращает число токенов в корзине через elapsed после состояния tokens
+func refill(p Policy, tokens float64, elapsed time.Duration) float64 {
+	tokens += elapsed.Seconds() * float64(p.Limit) / p.Period.Seconds()
+	return min(tokens, float64(p.Limit))
+}
+
+// untilTokens — время, за которое в корзине наберётся want токенов
+func untilTokens(p Policy, tokens, want float64) time.Duration {
+	if tokens >= want {
+		return 0
+	}
+	return time.Duration((want - tokens) / float64(p.Limit) * float64(p.Period))
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Policy
+		wantErr bool
+	}{
+		{"20/1m", Policy{Name: "p", Limit: 20, Period: time.Minute}, false},
+		{" 5 / 10s ", Policy{Name: "p", Limit: 5, Period: 10 * time.Second}, false},
+		{"20", Policy{}, true},
+		{"0/1m", Policy{}, true},
+		{"-1/1m", Policy{}, true},
+		{"x/1m", Policy{}, true},
+		{"20/minute", Policy{}, true},
+		{"20/0s", Policy{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePolicy("p", tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %+v, %v; want %+v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"/game", "/game", true},
+		{"/game/sudoku", "/game", true},
+		{"/gamer", "/game", false},
+		{"/gamer", "/game/", false},
+		{"/game/sudoku", "/game/", true},
+		{"/auth/login", "/", true},
+		{"/aut", "/auth", false},
+		{"", "/game", false},
+	}
+	for _, tt := range tests {
+		if got := hasPathPrefix(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("hasPathPrefix(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	def := Policy{Name: "default", Limit: 100, Period: time.Minute}
+	login := Policy{Name: "login", Limit: 5, Period: time.Minute}
+	game := Policy{Name: "game", Limit: 50, Period: time.Minute}
+	rules := []Rule{
+		{Prefix: "/", Policy: def},
+		{Prefix: "/auth/login", Policy: login},
+		{Prefix: "/game", Policy: game},
+	}
+
+	tests := []struct {
+		path   string
+		want   Policy
+		wantOK bool
+	}{
+		{"/auth/login", login, true},
+		{"/auth/login/mfa", login, true},
+		{"/auth/loginx", def, true},
+		{"/game/sudoku/1", game, true},
+		{"/gamer", def, true},
+		{"/users/me", def, true},
+	}
+	for _, tt := range tests {
+		got, ok := Match(rules, tt.path)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("Match(%q) = %s, %v; want %s, %v", tt.path, got.Name, ok, tt.want.Name, tt.wantOK)
+		}
+	}
+
+	if _, ok := Match(rules[1:], "/users/me"); ok {
+		t.Errorf("Match without a catch-all rule matched /users/me")
+	}
+}
+
+func TestRefill(t *testing.T) {
+	p := Policy{Limit: 10, Period: 10 * time.Second}
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{"no time passed", 3, 0, 3},
+		{"one token per second", 3, 2 * time.Second, 5},
+		{"fractional", 0, 500 * time.Millisecond, 0.5},
+		{"capped at limit", 8, time.Minute, 10},
+		{"full stays full", 10, time.Second, 10},
+	}
+	for _, tt := range tests {
+		if got := refill(p, tt.tokens, tt.elapsed); got != tt.want {
+			t.Errorf("%s: refill(%v, %v) = %v, want %v", tt.name, tt.tokens, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	ctx := context.Background()
+	p := Policy{Name: "p", Limit: 2, Period: 2 * time.Second}
+	start := time.Unix(1000, 0)
+
+	tests := []struct {
+		name      string
+		key       string
+		at        time.Duration
+		allowed   bool
+		remaining int
+		retry     time.Duration
+	}{
+		{"first", "a", 0, true, 1, 0},
+		{"second", "a", 0, true, 0, 0},
+		{"empty", "a", 0, false, 0, time.Second},
+		{"other key", "b", 0, true, 1, 0},
+		{"half refilled", "a", 500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+		{"refilled", "a", time.Second, true, 0, 0},
+	}
+	s := NewMemoryStore()
+	for _, tt := range tests {
+		res, err := s.Take(ctx, tt.key, p, start.Add(tt.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != tt.allowed || res.Remaining != tt.remaining || res.RetryAfter != tt.retry {
+			t.Errorf("%s: Take() = %+v, want allowed=%v remaining=%d retry=%v",
+				tt.name, res, tt.allowed, tt.remaining, tt.retry)
+		}
+	}
+}
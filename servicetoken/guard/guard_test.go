@@ -0,0 +1,75 @@
+package guard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"servicetoken"
+
+	"github.com/gin-gonic/gin"
+)
+
+var secret = []byte("test-secret")
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestGuard(t *testing.T) {
+	verifier := servicetoken.NewVerifier("users", secret, "gateway", "game")
+
+	r := gin.New()
+	r.Use(ServiceAuth(verifier))
+	echo := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetHeader("X-User-ID")+"|"+c.GetHeader("X-User-Role")+"|"+c.GetString(ServiceKey))
+	}
+	r.GET("/open", echo)
+	r.GET("/internal", RequireService("game"), echo)
+	r.GET("/admin", RequireRole("admin"), echo)
+
+	tests := []struct {
+		name     string
+		path     string
+		from     string // пусто — без токена
+		userID   string
+		role     string
+		spoofed  string // X-User-ID, подставленный клиентом
+		wantCode int
+		wantBody string
+	}{
+		{"no token", "/open", "", "", "", "", http.StatusUnauthorized, ""},
+		{"unknown service", "/open", "auth", "u1", "", "", http.StatusUnauthorized, ""},
+		{"user from token", "/open", "gateway", "u1", "user", "", http.StatusOK, "u1|user|gateway"},
+		{"spoofed header replaced", "/open", "gateway", "u1", "user", "admin-id", http.StatusOK, "u1|user|gateway"},
+		{"spoofed header dropped for background job", "/open", "game", "", "", "admin-id", http.StatusOK, "||game"},
+		{"internal from gateway", "/internal", "gateway", "u1", "", "", http.StatusForbidden, ""},
+		{"internal from game", "/internal", "game", "", "", "", http.StatusOK, "||game"},
+		{"admin role", "/admin", "gateway", "u1", "admin", "", http.StatusOK, "u1|admin|gateway"},
+		{"user role", "/admin", "gateway", "u1", "user", "", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.spoofed != "" {
+				req.Header.Set("X-User-ID", tt.spoofed)
+				req.Header.Set("X-User-Role", "admin")
+			}
+			if tt.from != "" {
+				err := servicetoken.NewSigner(tt.from, secret).SignRequest(req, "users", tt.userID, tt.role)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
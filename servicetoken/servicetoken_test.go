@@ -0,0 +1,102 @@
+package servicetoken
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var secret = []byte("test-secret")
+
+// signClaims подписывает произвольные claims, чтобы проверить отказы Verify
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func registered(issuer, audience string, issued, expires time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   "user-1",
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(issued),
+		ExpiresAt: jwt.NewNumericDate(expires),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	v := NewVerifier("game", secret, "gateway", "tournament")
+	now := time.Now()
+
+	valid, err := NewSigner("gateway", secret).Sign("game", "user-1", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSecret, _ := NewSigner("gateway", []byte("other")).Sign("game", "user-1", "")
+	otherAudience, _ := NewSigner("gateway", secret).Sign("users", "user-1", "")
+	unknownPeer, _ := NewSigner("auth", secret).Sign("game", "user-1", "")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"valid", valid, nil},
+		{"missing", "", ErrMissing},
+		{"garbage", "not-a-jwt", ErrInvalidToken},
+		{"wrong secret", otherSecret, ErrInvalidToken},
+		{"wrong audience", otherAudience, ErrInvalidToken},
+		{"unknown peer", unknownPeer, ErrUnknownPeer},
+		{"expired", signClaims(t, jwt.SigningMethodHS256, secret, Claims{
+			RegisteredClaims: registered("gateway", "game", now.Add(-5*time.Minute), now.Add(-time.Minute)),
+		}), ErrInvalidToken},
+		{"expired within leeway", signClaims(t, jwt.SigningMethodHS256, secret, Claims{
+			RegisteredClaims: registered("gateway", "game", now.Add(-time.Minute), now.Add(-10*time.Second)),
+		}), nil},
+		{"no expiry", signClaims(t, jwt.SigningMethodHS256, secret, Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Issuer: "gateway", Audience: jwt.ClaimStrings{"game"}},
+		}), ErrInvalidToken},
+		{"issued in the future", signClaims(t, jwt.SigningMethodHS256, secret, Claims{
+			RegisteredClaims: registered("gateway", "game", now.Add(5*time.Minute), now.Add(6*time.Minute)),
+		}), ErrInvalidToken},
+		{"other algorithm", signClaims(t, jwt.SigningMethodHS512, secret, Claims{
+			RegisteredClaims: registered("gateway", "game", now, now.Add(time.Minute)),
+		}), ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.Issuer != "gateway" || claims.Subject != "user-1") {
+				t.Errorf("Verify() claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://users/users/1", nil)
+	if err := NewSigner("auth", secret).SignRequest(req, "users", "user-1", "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := NewVerifier("users", secret, "auth").Verify(req.Header.Get(Header))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.Issuer != "auth" || claims.Subject != "user-1" || claims.Role != "user" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != time.Minute {
+		t.Errorf("token lifetime = %v, want %v", ttl, time.Minute)
+	}
+}
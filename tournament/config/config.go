@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 
 	"github.com/joho/godotenv"
@@ -20,6 +21,9 @@ type Config struct {
 	Port           string
 	GameServiceURL string
 	UserServiceURL string
+
+	// Общий секрет для подписи и проверки запросов между сервисами
+	ServiceTokenSecret string
 }
 
 func LoadConfig() (*Config, error) {
@@ -28,6 +32,11 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	secret := os.Getenv("SERVICE_TOKEN_SECRET")
+	if secret == "" {
+		return nil, errors.New("missing required environment variable: SERVICE_TOKEN_SECRET")
+	}
+
 	return &Config{
 		DBHost:     os.Getenv("DBHost"),
 		DBPort:     os.Getenv("DBPort"),
@@ -42,5 +51,7 @@ func LoadConfig() (*Config, error) {
 		Port:           os.Getenv("PORT"),
 		GameServiceURL: os.Getenv("GAME_SERVICE_URL"),
 		UserServiceURL: os.Getenv("USER_SERVICE_URL"),
+
+		ServiceTokenSecret: secret,
 	}, nil
 }
@@ -71,3 +71,30 @@ func (d *Database) DeleteParticipant(ctx context.Context, tournamentID, userID s
 
 	return nil
 }
+
+// CountUserTournaments возвращает число турниров, в которых участвовал пользователь
+func (d *Database) CountUserTournaments(ctx context.Context, userID string) (int, error) {
+	var count int
+	const query = `SELECT COUNT(*) FROM tournament_participants WHERE user_id = $1`
+
+	if err := d.DB.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("count user tournaments: %w", err)
+	}
+	return count, nil
+}
+
+// GetUserResults возвращает места пользователя в завершённых турнирах
+func (d *Database) GetUserResults(ctx context.Context, userID string) ([]models.TournamentResult, error) {
+	const query = `
+		SELECT tournament_id, user_id, username, score, rank, solved_count
+		FROM tournament_results
+		WHERE user_id = $1
+		ORDER BY finished_at DESC
+	`
+
+	results := []models.TournamentResult{}
+	if err := d.DB.SelectContext(ctx, &results, query, userID); err != nil {
+		return nil, fmt.Errorf("get user results: %w", err)
+	}
+	return results, nil
+}
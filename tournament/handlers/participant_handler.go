@@ -107,3 +107,29 @@ func (h *TournamentHandler) DeleteParticipant(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully deleted participant"})
 }
+
+// GetUserSummary отдаёт участие пользователя в турнирах (используется сервисом game для достижений)
+func (h *TournamentHandler) GetUserSummary(c *gin.Context) {
+	userID := c.Param("id")
+	ctx := c.Request.Context()
+
+	joined, err := h.db.CountUserTournaments(ctx, userID)
+	if err != nil {
+		h.logger.Errorf("failed to count tournaments of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal error"})
+		return
+	}
+
+	results, err := h.db.GetUserResults(ctx, userID)
+	if err != nil {
+		h.logger.Errorf("failed to get results of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.UserTournamentSummary{
+		UserID:            userID,
+		TournamentsJoined: joined,
+		Results:           results,
+	})
+}
@@ -43,7 +43,7 @@ func (h *TournamentHandler) GetSudoku(c *gin.Context) {
 	cfg := c.MustGet("config").(*config.Config)
 	sudokuURL := fmt.Sprintf("%s/sudoku?difficulty=%s", cfg.GameServiceURL, difficulty)
 
-	sudokuResp, err := http.Get(sudokuURL)
+	sudokuResp, err := h.getFromGame(c, sudokuURL)
 	if err != nil {
 		h.logger.Errorf("failed to fetch sudoku: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch sudoku"})
@@ -99,7 +99,7 @@ func (h *TournamentHandler) GetSudokuByID(c *gin.Context) {
 	cfg := c.MustGet("config").(*config.Config)
 	sudokuURL := fmt.Sprintf("%s/sudoku/%s", cfg.GameServiceURL, id)
 
-	sudokuResp, err := http.Get(sudokuURL)
+	sudokuResp, err := h.getFromGame(c, sudokuURL)
 	if err != nil {
 		h.logger.Errorf("failed to fetch sudoku: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch sudoku"})
@@ -123,6 +123,18 @@ func (h *TournamentHandler) GetSudokuByID(c *gin.Context) {
 	c.JSON(http.StatusOK, sudoku)
 }
 
+// getFromGame запрашивает сервис game от имени текущего пользователя
+func (h *TournamentHandler) getFromGame(c *gin.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.signer.SignRequest(req, "game", c.GetString("user_id"), c.GetString("user_role")); err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
 func (h *TournamentHandler) ReportSolved(c *gin.Context) {
 
 	var req models.SudokuSolvedRequest
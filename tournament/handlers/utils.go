@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"servicetoken"
 	"tournament/database"
 	"tournament/models"
 
@@ -11,11 +12,12 @@ import (
 
 type TournamentHandler struct {
 	db     *database.Database
+	signer *servicetoken.Signer
 	logger *logrus.Logger
 }
 
-func NewTournamentHandler(db *database.Database, logger *logrus.Logger) *TournamentHandler {
-	return &TournamentHandler{db: db, logger: logger}
+func NewTournamentHandler(db *database.Database, signer *servicetoken.Signer, logger *logrus.Logger) *TournamentHandler {
+	return &TournamentHandler{db: db, signer: signer, logger: logger}
 }
 
 func prepareTournamentResults(ctx context.Context, db *database.Database, tournamentID string) ([]models.TournamentResult, error) {
@@ -2,10 +2,13 @@ package main
 
 import (
 	"log"
+	"servicetoken"
+	"servicetoken/guard"
 	"tournament/config"
 	"tournament/database"
 	"tournament/handlers"
 	"tournament/middleware"
+	"tournament/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -25,19 +28,23 @@ func main() {
 	}
 
 	// Инициализация обработчиков
-	tournamentHandler := handlers.NewTournamentHandler(db, logger)
+	tournamentHandler := handlers.NewTournamentHandler(db, servicetoken.NewSigner("tournament", []byte(cfg.ServiceTokenSecret)), logger)
 
 	// Настройка роутера
 	router := gin.Default()
 
+	// Запросы принимаются только от шлюза и сервиса game
+	router.Use(guard.ServiceAuth(servicetoken.NewVerifier("tournament", []byte(cfg.ServiceTokenSecret), "gateway", "game")))
 	router.Use(middleware.ExtractUserIDHeader(cfg))
 
+	staff := guard.RequireRole(models.RoleModerator, models.RoleAdmin)
+
 	// Базовые CRUD операции
 	router.GET("/", tournamentHandler.GetTournaments)
 	router.GET("/:id", tournamentHandler.GetTournament)
-	router.POST("/", tournamentHandler.CreateTournament)
-	router.PATCH("/:id", tournamentHandler.PatchTournament)
-	router.DELETE("/:id", tournamentHandler.DeleteTournament)
+	router.POST("/", staff, tournamentHandler.CreateTournament)
+	router.PATCH("/:id", staff, tournamentHandler.PatchTournament)
+	router.DELETE("/:id", staff, tournamentHandler.DeleteTournament)
 
 	router.GET("/current", tournamentHandler.GetCurrentTournament)
 
@@ -49,13 +56,16 @@ func main() {
 	router.GET("/:id/dashboard", tournamentHandler.GetDashboard)
 	router.GET("/:id/results", tournamentHandler.GetResults)
 
-	router.POST("/:id/start", tournamentHandler.StartTournament)
-	router.POST("/:id/finish", tournamentHandler.FinishTournament)
+	router.POST("/:id/start", staff, tournamentHandler.StartTournament)
+	router.POST("/:id/finish", staff, tournamentHandler.FinishTournament)
 
 	router.POST("/sudoku", tournamentHandler.GetSudoku)
 	router.POST("/sudoku/:id", tournamentHandler.GetSudokuByID)
 	router.POST("/sudoku/:id/solved", tournamentHandler.ReportSolved)
 
+	// Сводку по истории пользователя запрашивает только сервис game для автовыдачи наград
+	router.GET("/users/:id/summary", guard.RequireService("game"), tournamentHandler.GetUserSummary)
+
 	// Запуск сервера
 	if err := router.Run(":" + cfg.Port); err != nil {
 		log.Fatal("Error starting server:", err)
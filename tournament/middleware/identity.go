@@ -11,6 +11,9 @@ func ExtractUserIDHeader(cfg *config.Config) gin.HandlerFunc {
 		if userID := c.GetHeader("X-User-ID"); userID != "" {
 			c.Set("user_id", userID)
 		}
+		if userRole := c.GetHeader("X-User-Role"); userRole != "" {
+			c.Set("user_role", userRole)
+		}
 		c.Set("config", cfg)
 		c.Next()
 	}
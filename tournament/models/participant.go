@@ -20,3 +20,10 @@ type RegisterParticipantRequest struct {
 type DeleteParticipantRequest struct {
 	UserID string `json:"user_id"`
 }
+
+// UserTournamentSummary — участие пользователя в турнирах и его места в завершённых
+type UserTournamentSummary struct {
+	UserID            string             `json:"user_id"`
+	TournamentsJoined int                `json:"tournaments_joined"`
+	Results           []TournamentResult `json:"results"`
+}
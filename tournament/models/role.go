@@ -0,0 +1,8 @@
+package models
+
+// Роли пользователей; выдаются сервисом users и приходят в заголовке X-User-Role
+const (
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
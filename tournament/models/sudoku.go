@@ -8,7 +8,6 @@ type GetSudokuRequest struct {
 type SudokuResponse struct {
 	ID               string  `json:"id"`
 	InitialField     string  `json:"initial_field"`
-	Solution         string  `json:"solution"`
 	Complexity       string  `json:"complexity"`
 	CreatedAt        string  `json:"created_at"`
 	SolveAttempts    int64   `json:"solve_attempts"`
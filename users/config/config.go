@@ -16,6 +16,10 @@ type Config struct {
 
 	ServerPort string
 	LogLevel   string
+	AdminEmail string // необязательно: пользователь с этой почтой получает роль admin при старте
+
+	// Общий секрет для проверки запросов от шлюза и других сервисов
+	ServiceTokenSecret string
 }
 
 func LoadConfig() (*Config, error) {
@@ -30,6 +34,9 @@ func LoadConfig() (*Config, error) {
 
 		ServerPort: getEnv("SERVER_PORT"),
 		LogLevel:   getEnv("LOG_LEVEL"),
+		AdminEmail: os.Getenv("ADMIN_EMAIL"),
+
+		ServiceTokenSecret: getEnv("SERVICE_TOKEN_SECRET"),
 	}
 
 	return cfg, nil
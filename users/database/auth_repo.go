@@ -10,7 +10,7 @@ import (
 
 func (d *Database) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	const query = `
-		SELECT id, username, email, password, created_at, updated_at
+		SELECT id, username, email, password, role, email_verified, token_version, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
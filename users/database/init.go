@@ -58,6 +58,12 @@ func (d *Database) createTables(ctx context.Context) error {
 			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 		)`,
+		// Пароли хранит сервис auth; здесь остаются только ещё не перенесённые хэши
+		`ALTER TABLE users ALTER COLUMN password DROP NOT NULL`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'player'`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0`,
+		// Пользователи, зарегистрированные до подтверждения почты, считаются подтверждёнными
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT TRUE`,
 		`CREATE TABLE IF NOT EXISTS user_info (
 			user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 			firstname VARCHAR(20) NOT NULL DEFAULT '',
@@ -79,6 +85,10 @@ func (d *Database) createTables(ctx context.Context) error {
 			avatar_url TEXT NOT NULL,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)`,
+		`CREATE TABLE IF NOT EXISTS processed_requests (
+			idempotency_key TEXT PRIMARY KEY,
+			processed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
 	}
 
 	for _, q := range queries {
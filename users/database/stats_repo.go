@@ -49,7 +49,10 @@ func (d *Database) GetUserStatistics(ctx context.Context, userID string) ([]mode
 	return stats, nil
 }
 
-func (d *Database) UpdateDifficultyStats(ctx context.Context, userID, difficulty string, timeSeconds int) error {
+// UpdateDifficultyStats засчитывает решение. Непустой idempotencyKey
+// гарантирует, что повтор того же запроса не учтётся дважды;
+// false — запрос с этим ключом уже был обработан.
+func (d *Database) UpdateDifficultyStats(ctx context.Context, userID, difficulty string, timeSeconds int, idempotencyKey string) (bool, error) {
 	const query = `
 		UPDATE user_difficulty_stats
 		SET
@@ -63,9 +66,36 @@ func (d *Database) UpdateDifficultyStats(ctx context.Context, userID, difficulty
 		WHERE user_id = $2 AND difficulty = $3
 	`
 
-	_, err := d.DB.ExecContext(ctx, query, timeSeconds, userID, difficulty)
+	tx, err := d.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("update difficulty stats: %w", err)
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if idempotencyKey != "" {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO processed_requests (idempotency_key)
+			VALUES ($1)
+			ON CONFLICT (idempotency_key) DO NOTHING
+		`, idempotencyKey)
+		if err != nil {
+			return false, fmt.Errorf("save idempotency key: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("rows affected: %w", err)
+		}
+		if affected == 0 {
+			return false, nil
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, query, timeSeconds, userID, difficulty); err != nil {
+		return false, fmt.Errorf("update difficulty stats: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
 	}
-	return nil
+	return true, nil
 }
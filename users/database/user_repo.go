@@ -10,7 +10,7 @@ import (
 
 func (d *Database) GetUsers(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	const query = `SELECT id, username, email, created_at, updated_at FROM users ORDER BY created_at DESC`
+	const query = `SELECT id, username, email, role, email_verified, created_at, updated_at FROM users ORDER BY created_at DESC`
 
 	if err := d.DB.SelectContext(ctx, &users, query); err != nil {
 		return nil, fmt.Errorf("get users: %w", err)
@@ -20,7 +20,7 @@ func (d *Database) GetUsers(ctx context.Context) ([]models.User, error) {
 
 func (d *Database) GetUser(ctx context.Context, id string) (*models.User, error) {
 	const query = `
-		SELECT id, username, email, password, created_at, updated_at
+		SELECT id, username, email, password, role, email_verified, token_version, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -38,8 +38,8 @@ func (d *Database) GetUser(ctx context.Context, id string) (*models.User, error)
 
 func (d *Database) CreateUser(ctx context.Context, user *models.User) error {
 	const query = `
-		INSERT INTO users (id, username, email, password, created_at, updated_at)
-		VALUES (:id, :username, :email, :password, :created_at, :updated_at)
+		INSERT INTO users (id, username, email, role, email_verified, created_at, updated_at)
+		VALUES (:id, :username, :email, :role, :email_verified, :created_at, :updated_at)
 	`
 
 	_, err := d.DB.NamedExecContext(ctx, query, user)
@@ -54,7 +54,8 @@ func (d *Database) UpdateUser(ctx context.Context, user *models.User) error {
 		UPDATE users
 		SET username = :username,
 		    email = :email,
-		    password = :password,
+		    email_verified = :email_verified,
+		    token_version = :token_version,
 		    updated_at = :updated_at
 		WHERE id = :id
 	`
@@ -81,3 +82,81 @@ func (d *Database) DeleteUser(ctx context.Context, id string) (bool, error) {
 
 	return rows > 0, nil
 }
+
+// SetUserRole меняет роль пользователя; false — пользователя нет
+func (d *Database) SetUserRole(ctx context.Context, id, role string) (bool, error) {
+	const query = `
+		UPDATE users
+		SET role = $2, token_version = token_version + 1, updated_at = NOW()
+		WHERE id = $1
+	`
+	result, err := d.DB.ExecContext(ctx, query, id, role)
+	if err != nil {
+		return false, fmt.Errorf("set user role: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// BumpTokenVersion делает недействительными все выданные пользователю токены; false — пользователя нет
+func (d *Database) BumpTokenVersion(ctx context.Context, id string) (bool, error) {
+	const query = `UPDATE users SET token_version = token_version + 1 WHERE id = $1`
+	result, err := d.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("bump token version: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// PromoteAdmin выдаёт роль admin пользователю с указанной почтой
+func (d *Database) PromoteAdmin(ctx context.Context, email string) error {
+	const query = `
+		UPDATE users
+		SET role = 'admin', token_version = token_version + 1, updated_at = NOW()
+		WHERE email = $1 AND role <> 'admin'
+	`
+	if _, err := d.DB.ExecContext(ctx, query, email); err != nil {
+		return fmt.Errorf("promote admin: %w", err)
+	}
+	return nil
+}
+
+// VerifyEmail подтверждает почту, если она не менялась с момента отправки письма; false — пользователя с такой почтой нет
+func (d *Database) VerifyEmail(ctx context.Context, id, email string) (bool, error) {
+	const query = `UPDATE users SET email_verified = TRUE, updated_at = NOW() WHERE id = $1 AND email = $2`
+	result, err := d.DB.ExecContext(ctx, query, id, email)
+	if err != nil {
+		return false, fmt.Errorf("verify email: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// ClearLegacyPassword удаляет перенесённый в сервис auth хэш пароля; false — пользователя нет
+func (d *Database) ClearLegacyPassword(ctx context.Context, id string) (bool, error) {
+	const query = `UPDATE users SET password = NULL WHERE id = $1`
+
+	result, err := d.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("clear legacy password: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
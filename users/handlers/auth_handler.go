@@ -1,42 +1,130 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"users/models"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func (h *UserHandler) AuthUser(c *gin.Context) {
-	var req models.AuthRequest
+// GetAuthState возвращает роль и версию токенов пользователя для сервиса auth и шлюза
+func (h *UserHandler) GetAuthState(c *gin.Context) {
+	id := c.Param("id")
+
+	user, err := h.db.GetUser(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to get user %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ToAuthResponse(*user))
+}
+
+// RevokeTokens повышает версию токенов пользователя (выход со всех устройств)
+func (h *UserHandler) RevokeTokens(c *gin.Context) {
+	id := c.Param("id")
+
+	ok, err := h.db.BumpTokenVersion(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Errorf("failed to revoke tokens for user %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tokens revoked"})
+}
+
+// GetAuthStateByEmail ищет пользователя по почте для писем подтверждения и сброса пароля
+func (h *UserHandler) GetAuthStateByEmail(c *gin.Context) {
+	email := c.Query("email")
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		return
+	}
+
+	user, err := h.db.GetUserByEmail(c.Request.Context(), email)
+	if err != nil {
+		h.logger.Errorf("failed to get user by email: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal error"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ToAuthResponse(*user))
+}
+
+// VerifyEmail отмечает почту подтверждённой; 404 — пользователь удалён или сменил почту
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.VerifyEmailRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
-	ctx := c.Request.Context()
-	user, err := h.db.GetUserByEmail(ctx, req.Email)
+	ok, err := h.db.VerifyEmail(c.Request.Context(), id, req.Email)
 	if err != nil {
-		h.logger.Errorf("auth error — failed to find user by email %s: %v", req.Email, err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		h.logger.Errorf("failed to verify email for user %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
 		return
 	}
-	if user == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		h.logger.Warnf("auth failed — bad password for user %s", user.ID)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+// GetLegacyPassword отдаёт сервису auth хэш пароля, сохранённый здесь до переноса паролей;
+// 404 — пользователя нет или хэш уже перенесён
+func (h *UserHandler) GetLegacyPassword(c *gin.Context) {
+	id := c.Param("id")
+
+	user, err := h.db.GetUser(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("failed to get user %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal error"})
+		return
+	}
+	if user.Password == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No legacy password"})
 		return
 	}
 
-	resp := models.AuthResponse{
-		ID:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
+	c.JSON(http.StatusOK, models.LegacyPasswordResponse{PasswordHash: *user.Password})
+}
+
+// DeleteLegacyPassword удаляет хэш после того, как сервис auth его сохранил
+func (h *UserHandler) DeleteLegacyPassword(c *gin.Context) {
+	id := c.Param("id")
+
+	ok, err := h.db.ClearLegacyPassword(c.Request.Context(), id)
+	if err != nil {
+		h.logger.Errorf("failed to clear legacy password for user %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear password"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
 	}
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, gin.H{"message": "Legacy password cleared"})
 }
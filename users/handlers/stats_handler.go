@@ -43,11 +43,16 @@ func (h *UserHandler) UpdateUserStats(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	if err := h.db.UpdateDifficultyStats(ctx, req.UserID, req.Difficulty, req.TimeSeconds); err != nil {
+	applied, err := h.db.UpdateDifficultyStats(ctx, req.UserID, req.Difficulty, req.TimeSeconds, c.GetHeader("Idempotency-Key"))
+	if err != nil {
 		h.logger.Errorf("failed to update stats for %s (%s): %v", req.UserID, req.Difficulty, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update difficulty stats"})
 		return
 	}
+	if !applied {
+		c.JSON(http.StatusOK, gin.H{"message": "Statistics already updated"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Statistics updated"})
 }
@@ -7,8 +7,9 @@ import (
 	"time"
 	"users/models"
 
+	"servicetoken/guard"
+
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (h *UserHandler) GetUsers(c *gin.Context) {
@@ -68,7 +69,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	newUser := models.NewUser(req.Username, req.Email, req.Password)
+	newUser := models.NewUser(req.Username, req.Email)
 
 	if err := h.db.CreateUser(ctx, newUser); err != nil {
 		h.logger.Errorf("failed to create user: %v", err)
@@ -94,8 +95,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 func (h *UserHandler) PatchUser(c *gin.Context) {
 	id := c.Param("id")
 
-	userID, exists := c.Get("user_id")
-	if !exists || userID != id {
+	if !isSelfOrAdmin(c, id) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to edit this user"})
 		return
 	}
@@ -107,8 +107,13 @@ func (h *UserHandler) PatchUser(c *gin.Context) {
 		return
 	}
 
+	if input.Password != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use /auth/password/change to change the password"})
+		return
+	}
+
 	// Отказаться от пустого PATCH
-	if input.Username == nil && input.Email == nil && input.Password == nil {
+	if input.Username == nil && input.Email == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Empty update payload"})
 		return
 	}
@@ -124,17 +129,10 @@ func (h *UserHandler) PatchUser(c *gin.Context) {
 	if input.Username != nil {
 		user.Username = *input.Username
 	}
-	if input.Email != nil {
+	if input.Email != nil && *input.Email != user.Email {
 		user.Email = *input.Email
-	}
-	if input.Password != nil {
-		hashed, err := bcrypt.GenerateFromPassword([]byte(*input.Password), bcrypt.DefaultCost)
-		if err != nil {
-			h.logger.Errorf("failed to hash password: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-			return
-		}
-		user.Password = string(hashed)
+		// Новую почту нужно подтвердить заново
+		user.EmailVerified = false
 	}
 	user.UpdatedAt = time.Now()
 
@@ -150,8 +148,8 @@ func (h *UserHandler) PatchUser(c *gin.Context) {
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
 
-	userID, exists := c.Get("user_id")
-	if !exists || userID != id {
+	// auth удаляет пользователя, если регистрацию не удалось завершить
+	if c.GetString(guard.ServiceKey) != "auth" && !isSelfOrAdmin(c, id) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to delete this user"})
 		return
 	}
@@ -171,3 +169,37 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
+
+// UpdateUserRole меняет роль пользователя (только для admin)
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !models.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of player, moderator, admin"})
+		return
+	}
+	// Иначе последний администратор может случайно лишить себя прав
+	if c.GetString("user_id") == id {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+
+	ok, err := h.db.SetUserRole(c.Request.Context(), id, req.Role)
+	if err != nil {
+		h.logger.Errorf("failed to set role for user %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	h.logger.Infof("user %s role changed to %s by %s", id, req.Role, c.GetString("user_id"))
+	c.JSON(http.StatusOK, gin.H{"id": id, "role": req.Role})
+}
+
+func isSelfOrAdmin(c *gin.Context, id string) bool {
+	return c.GetString("user_id") == id || c.GetString("user_role") == models.RoleAdmin
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"os"
+	"servicetoken"
+	"servicetoken/guard"
 	"users/config"
 	"users/database"
 	"users/handlers"
 	"users/middleware"
+	"users/models"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
@@ -29,25 +33,43 @@ func main() {
 		logrus.Fatalf("failed to init database: %v", err)
 	}
 
+	if cfg.AdminEmail != "" {
+		if err := db.PromoteAdmin(context.Background(), cfg.AdminEmail); err != nil {
+			logrus.Fatalf("failed to promote admin: %v", err)
+		}
+	}
+
 	// Обработчики
 	userHandler := handlers.NewUserHandler(db, logger)
 
 	// Роутер
 	router := gin.Default()
 
+	// Запросы принимаются только от шлюза и сервисов auth и game
+	router.Use(guard.ServiceAuth(servicetoken.NewVerifier("users", []byte(cfg.ServiceTokenSecret), "gateway", "auth", "game")))
 	router.Use(middleware.ExtractUserIDHeader())
+	router.Use(middleware.ExtractUserRoleHeader())
 
 	// Публичные маршруты
 	router.GET("/check-username", userHandler.CheckUsername)
 	router.GET("/check-email", userHandler.CheckEmail)
-	router.POST("/", userHandler.CreateUser)
-	router.POST("/auth", userHandler.AuthUser)
+
+	// Внутренние маршруты для auth и шлюза (шлюз их не проксирует)
+	authOnly := guard.RequireService("auth")
+	router.POST("/", authOnly, userHandler.CreateUser)
+	router.GET("/:id/auth", guard.RequireService("auth", "gateway"), userHandler.GetAuthState)
+	router.POST("/:id/revoke-tokens", authOnly, userHandler.RevokeTokens)
+	router.GET("/by-email", authOnly, userHandler.GetAuthStateByEmail)
+	router.POST("/:id/verify-email", authOnly, userHandler.VerifyEmail)
+	router.GET("/:id/legacy-password", authOnly, userHandler.GetLegacyPassword)
+	router.DELETE("/:id/legacy-password", authOnly, userHandler.DeleteLegacyPassword)
 
 	// Защищённые маршруты
 	router.GET("/", userHandler.GetUsers)
 	router.GET("/:id", userHandler.GetUser)
 	router.PATCH("/:id", userHandler.PatchUser)
 	router.DELETE("/:id", userHandler.DeleteUser)
+	router.PUT("/:id/role", guard.RequireRole(models.RoleAdmin), userHandler.UpdateUserRole)
 
 	router.GET("/me", userHandler.GetMe)
 	router.GET("/me/info", userHandler.GetMyUserInfo)
@@ -58,7 +80,8 @@ func main() {
 	router.GET("/:id/avatar", userHandler.GetAvatar)
 
 	router.GET("/:id/statistics", userHandler.GetUserStatistics)
-	router.PATCH("/:id/statistics", userHandler.UpdateUserStats)
+	// Статистику обновляет только сервис game
+	router.PATCH("/:id/statistics", guard.RequireService("game"), userHandler.UpdateUserStats)
 
 	// Запуск
 	logger.Infof("Server starting on port %s", cfg.ServerPort)
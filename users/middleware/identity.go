@@ -13,11 +13,11 @@ func ExtractUserIDHeader() gin.HandlerFunc {
 	}
 }
 
-// func ExtractUserRoleHeader() gin.HandlerFunc {
-// 	return func(c *gin.Context) {
-// 		if userRole := c.GetHeader("X-User-Role"); userRole != "" {
-// 			c.Set("user_role", userRole)
-// 		}
-// 		c.Next()
-// 	}
-// }
+func ExtractUserRoleHeader() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userRole := c.GetHeader("X-User-Role"); userRole != "" {
+			c.Set("user_role", userRole)
+		}
+		c.Next()
+	}
+}
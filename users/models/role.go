@@ -0,0 +1,16 @@
+package models
+
+// Роли пользователей; попадают в JWT и проверяются остальными сервисами
+const (
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+func IsValidRole(role string) bool {
+	return role == RolePlayer || role == RoleModerator || role == RoleAdmin
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -9,61 +9,90 @@ import (
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required,min=5"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-}
-
-type AuthRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
 }
 
 type AuthResponse struct {
 	ID       string `json:"id"`
 	Email    string `json:"email"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
+
+	EmailVerified bool `json:"email_verified"`
+	TokenVersion  int  `json:"token_version"`
+}
+
+func ToAuthResponse(u User) AuthResponse {
+	return AuthResponse{
+		ID:       u.ID,
+		Email:    u.Email,
+		Username: u.Username,
+		Role:     u.Role,
+
+		EmailVerified: u.EmailVerified,
+		TokenVersion:  u.TokenVersion,
+	}
 }
 
 type User struct {
-	ID        string    `db:"id" json:"id"`
-	Username  string    `db:"username" json:"username"`
-	Email     string    `db:"email" json:"email"`
-	Password  string    `db:"password" json:"-"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID       string `db:"id" json:"id"`
+	Username string `db:"username" json:"username"`
+	Email    string `db:"email" json:"email"`
+	// Хэш пароля времён, когда пароли хранил этот сервис; сервис auth забирает его при первом входе
+	Password *string `db:"password" json:"-"`
+	Role     string  `db:"role" json:"role"`
+	// Сбрасывается при смене почты
+	EmailVerified bool `db:"email_verified" json:"email_verified"`
+	// Повышается при смене пароля или роли и выходе со всех устройств; старые токены перестают приниматься
+	TokenVersion int       `db:"token_version" json:"-"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
 }
 
-func NewUser(username, email, hashedPassword string) *User {
+func NewUser(username, email string) *User {
 	return &User{
 		ID:        uuid.New().String(),
 		Username:  username,
 		Email:     email,
-		Password:  hashedPassword,
+		Role:      RolePlayer,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 }
 
 type SafeUser struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 func ToSafeUser(u User) SafeUser {
 	return SafeUser{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:            u.ID,
+		Username:      u.Username,
+		Email:         u.Email,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
 	}
 }
 
+type VerifyEmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type LegacyPasswordResponse struct {
+	PasswordHash string `json:"password_hash"`
+}
+
 type UpdateUserInput struct {
 	Username *string `json:"username"`
 	Email    *string `json:"email"`
+	// Пароль меняется через сервис auth; поле нужно только чтобы отклонить такой запрос
 	Password *string `json:"password"`
 }
 